@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"sync"
 
 	"golang.org/x/oauth2"
 )
@@ -29,19 +30,19 @@ func WithToken(ctx context.Context, config *oauth2.Config, token *oauth2.Token)
 }
 
 func (w *withToken) applyAuth(i *Instance) error {
-	if _, ok := w.ctx.Value(oauth2.HTTPClient).(*http.Client); !ok {
-		w.ctx = context.WithValue(w.ctx, oauth2.HTTPClient, i.client)
+	client, ok := w.ctx.Value(oauth2.HTTPClient).(*http.Client)
+	if !ok {
+		client = i.client
+		w.ctx = context.WithValue(w.ctx, oauth2.HTTPClient, client)
 	}
-	if !w.token.Valid() {
-		s := w.config.TokenSource(w.ctx, w.token)
-		t, err := s.Token()
-		if err != nil {
-			return err
-		}
-		w.token = t
+	session := &sessionTokenSource{ctx: w.ctx, config: w.config, token: w.token}
+	token, err := session.Token()
+	if err != nil {
+		return err
 	}
-	i.client = w.config.Client(w.ctx, w.token)
-	instanceURL, ok := w.token.Extra("instance_url").(string)
+	i.session = session
+	i.client = &http.Client{Transport: &oauth2.Transport{Source: session, Base: client.Transport}}
+	instanceURL, ok := token.Extra("instance_url").(string)
 	if !ok {
 		return errors.New("instance_url not available in the token")
 	}
@@ -54,3 +55,46 @@ type withToken struct {
 	token  *oauth2.Token
 	config *oauth2.Config
 }
+
+// sessionTokenSource holds the token of an Instance, refreshing it when it
+// expires or when Salesforce reports that the session is no longer valid,
+// which can happen before the token's expiry when a session is revoked.
+type sessionTokenSource struct {
+	ctx    context.Context
+	config *oauth2.Config
+
+	mu    sync.Mutex
+	token *oauth2.Token
+}
+
+// Token returns the current token, refreshing it when it is not valid.
+func (s *sessionTokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token.Valid() {
+		return s.token, nil
+	}
+	token, err := s.config.TokenSource(s.ctx, s.token).Token()
+	if err != nil {
+		return nil, err
+	}
+	s.token = token
+	return token, nil
+}
+
+// current returns the access token that will be used for the next request.
+func (s *sessionTokenSource) current() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.token.AccessToken
+}
+
+// invalidate forces the token to be refreshed by the next call to Token,
+// unless it has been refreshed since accessToken was used.
+func (s *sessionTokenSource) invalidate(accessToken string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token.AccessToken == accessToken {
+		s.token = &oauth2.Token{RefreshToken: s.token.RefreshToken}
+	}
+}
@@ -3,9 +3,11 @@ package sfdc_test
 import (
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/joefitzgerald/sfdc"
 	. "github.com/onsi/gomega"
@@ -125,6 +127,92 @@ func testAuthOptions(t *testing.T, when spec.G, it spec.S) {
 		})
 	})
 
+	when("the session is revoked before the token expires", func() {
+		var (
+			server    *httptest.Server
+			config    *oauth2.Config
+			refreshes int
+			requests  []string
+		)
+
+		it.Before(func() {
+			refreshes = 0
+			requests = nil
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/token" {
+					refreshes++
+					w.Header().Set("Content-Type", "application/json")
+					json.NewEncoder(w).Encode(&tokenJSON{
+						AccessToken:  "refreshed-access-token",
+						RefreshToken: "test-refresh-token",
+						TokenType:    "Bearer",
+						InstanceURL:  server.URL,
+						ExpiresIn:    1000,
+					})
+					return
+				}
+				if r.URL.Path == "/missing" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				requests = append(requests, r.Header.Get("Authorization"))
+				if r.Header.Get("Authorization") != "Bearer refreshed-access-token" {
+					w.WriteHeader(http.StatusUnauthorized)
+					w.Write([]byte(`[{"message":"Session expired or invalid","errorCode":"INVALID_SESSION_ID"}]`))
+					return
+				}
+				body, _ := io.ReadAll(r.Body)
+				Expect(string(body)).To(Equal(`{"Name":"Acme"}`))
+				w.Write([]byte(`{"id":"001000000000001AAA","success":true}`))
+			}))
+			config = &oauth2.Config{
+				Endpoint: oauth2.Endpoint{TokenURL: server.URL + "/token"},
+			}
+		})
+
+		it.After(func() {
+			server.Close()
+		})
+
+		type Account struct {
+			Name string `json:"Name"`
+		}
+
+		it("refreshes the token and retries the request once", func() {
+			token := (&oauth2.Token{
+				AccessToken:  "revoked-access-token",
+				RefreshToken: "test-refresh-token",
+				TokenType:    "Bearer",
+				Expiry:       time.Now().Add(time.Hour),
+			}).WithExtra(map[string]any{"instance_url": server.URL})
+			instance, err := sfdc.New(sfdc.WithToken(context.Background(), config, token))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(refreshes).To(Equal(0))
+
+			id, err := sfdc.NewEntity[Account](instance).Create(context.Background(), &Account{Name: "Acme"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(id).To(Equal(sfdc.ID("001000000000001AAA")))
+			Expect(refreshes).To(Equal(1))
+			Expect(requests).To(Equal([]string{"Bearer revoked-access-token", "Bearer refreshed-access-token"}))
+		})
+
+		it("returns the error when the session is still invalid", func() {
+			config.Endpoint.TokenURL = server.URL + "/missing"
+			token := (&oauth2.Token{
+				AccessToken:  "revoked-access-token",
+				RefreshToken: "test-refresh-token",
+				TokenType:    "Bearer",
+				Expiry:       time.Now().Add(time.Hour),
+			}).WithExtra(map[string]any{"instance_url": server.URL})
+			instance, err := sfdc.New(sfdc.WithToken(context.Background(), config, token))
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = sfdc.NewEntity[Account](instance).Create(context.Background(), &Account{Name: "Acme"})
+			Expect(err).To(HaveOccurred())
+			Expect(requests).To(HaveLen(1))
+		})
+	})
+
 	when("using a valid token", func() {
 		it("return", func() {
 
@@ -0,0 +1,412 @@
+package sfdc
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bulkUploadLimit is the largest CSV upload sent to a single ingest job.
+// Salesforce accepts up to 150MB once base64 encoded, and recommends no more
+// than 100MB of raw data.
+const bulkUploadLimit = 100 << 20
+
+// BulkOperation is the operation an ingest job performs.
+type BulkOperation string
+
+const (
+	BulkInsert     BulkOperation = "insert"
+	BulkUpdate     BulkOperation = "update"
+	BulkUpsert     BulkOperation = "upsert"
+	BulkDelete     BulkOperation = "delete"
+	BulkHardDelete BulkOperation = "hardDelete"
+)
+
+// Bulk API 2.0 job states.
+const (
+	JobStateOpen           = "Open"
+	JobStateUploadComplete = "UploadComplete"
+	JobStateInProgress     = "InProgress"
+	JobStateJobComplete    = "JobComplete"
+	JobStateFailed         = "Failed"
+	JobStateAborted        = "Aborted"
+)
+
+// BulkJob describes a Bulk API 2.0 job.
+type BulkJob struct {
+	ID                     string        `json:"id,omitempty"`
+	Object                 string        `json:"object,omitempty"`
+	Operation              BulkOperation `json:"operation,omitempty"`
+	Query                  string        `json:"query,omitempty"`
+	ExternalIDFieldName    string        `json:"externalIdFieldName,omitempty"`
+	ContentType            string        `json:"contentType,omitempty"`
+	LineEnding             string        `json:"lineEnding,omitempty"`
+	ColumnDelimiter        string        `json:"columnDelimiter,omitempty"`
+	State                  string        `json:"state,omitempty"`
+	NumberRecordsProcessed int           `json:"numberRecordsProcessed,omitempty"`
+	NumberRecordsFailed    int           `json:"numberRecordsFailed,omitempty"`
+	ErrorMessage           string        `json:"errorMessage,omitempty"`
+}
+
+// BulkOption configures a Bulk API 2.0 job.
+type BulkOption interface {
+	applyToBulk(b *bulkConfig)
+}
+
+type bulkConfig struct {
+	externalIDField string
+	pollInterval    time.Duration
+	maxRecords      int
+}
+
+type withExternalIDField struct {
+	field string
+}
+
+func (w *withExternalIDField) applyToBulk(b *bulkConfig) {
+	b.externalIDField = w.field
+}
+
+// ExternalIDField sets the external ID field used to match records in an
+// upsert job.
+func ExternalIDField(field string) BulkOption {
+	return &withExternalIDField{field: field}
+}
+
+type withPollInterval struct {
+	interval time.Duration
+}
+
+func (w *withPollInterval) applyToBulk(b *bulkConfig) {
+	b.pollInterval = w.interval
+}
+
+// PollInterval sets how often the status of a job is checked. The default is
+// five seconds.
+func PollInterval(interval time.Duration) BulkOption {
+	return &withPollInterval{interval: interval}
+}
+
+type withMaxRecords struct {
+	maxRecords int
+}
+
+func (w *withMaxRecords) applyToBulk(b *bulkConfig) {
+	b.maxRecords = w.maxRecords
+}
+
+// MaxRecords sets the maximum number of records fetched per page of query job
+// results. By default Salesforce picks the page size.
+func MaxRecords(n int) BulkOption {
+	return &withMaxRecords{maxRecords: n}
+}
+
+func newBulkConfig(options []BulkOption) *bulkConfig {
+	config := &bulkConfig{pollInterval: 5 * time.Second}
+	for i := range options {
+		options[i].applyToBulk(config)
+	}
+	return config
+}
+
+// BulkRecordResult is the outcome of ingesting a single record.
+type BulkRecordResult[T any] struct {
+	ID      ID
+	Created bool
+	Error   string
+	Record  T
+}
+
+// BulkIngestResult holds the outcome of an ingest.
+type BulkIngestResult[T any] struct {
+	Jobs        []BulkJob
+	Successful  []BulkRecordResult[T]
+	Failed      []BulkRecordResult[T]
+	Unprocessed []T
+}
+
+// Ingest loads records using Bulk API 2.0 ingest jobs. The records are
+// written as CSV, using the same fields that are selected when querying,
+// split across as many jobs as needed to stay within upload limits. Ingest
+// waits for every job to finish and returns the successful, failed and
+// unprocessed records of all jobs.
+func (e *Entity[T]) Ingest(ctx context.Context, operation BulkOperation, records []T, options ...BulkOption) (*BulkIngestResult[T], error) {
+	config := newBulkConfig(options)
+	if operation == BulkUpsert && config.externalIDField == "" {
+		return nil, errors.New("upsert jobs require an ExternalIDField")
+	}
+	columns := e.ingestColumns(operation)
+	keepID := operation != BulkInsert && operation != BulkUpsert
+	result := &BulkIngestResult[T]{}
+
+	var upload, row bytes.Buffer
+	w := csv.NewWriter(&row)
+	writeRow := func(values []string) error {
+		row.Reset()
+		w.Write(values)
+		w.Flush()
+		return w.Error()
+	}
+	if err := writeRow(csvHeader(columns)); err != nil {
+		return nil, err
+	}
+	header := bytes.Clone(row.Bytes())
+	upload.Write(header)
+	rows := 0
+	for i := range records {
+		r, err := newRecord(&records[i], e.readOnly, keepID)
+		if err != nil {
+			return result, err
+		}
+		if err := writeRow(csvRow(columns, r)); err != nil {
+			return result, err
+		}
+		if rows > 0 && upload.Len()+row.Len() > bulkUploadLimit {
+			if err := e.ingest(ctx, operation, upload.Bytes(), config, result); err != nil {
+				return result, err
+			}
+			upload.Reset()
+			upload.Write(header)
+			rows = 0
+		}
+		upload.Write(row.Bytes())
+		rows++
+	}
+	if rows > 0 {
+		if err := e.ingest(ctx, operation, upload.Bytes(), config, result); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// ingest runs a single ingest job for data and adds its results to result.
+func (e *Entity[T]) ingest(ctx context.Context, operation BulkOperation, data []byte, config *bulkConfig, result *BulkIngestResult[T]) error {
+	job, err := e.ingestJob(ctx, operation, data, config)
+	if job != nil {
+		result.Jobs = append(result.Jobs, *job)
+	}
+	if err != nil {
+		return err
+	}
+	return e.ingestResults(ctx, job.ID, result)
+}
+
+// ingestColumns returns the CSV columns written for operation. Deletes only
+// need the record Id, and inserts and upserts must not include it.
+func (e *Entity[T]) ingestColumns(operation BulkOperation) []csvColumn {
+	var example T
+	result := []csvColumn{}
+	for _, column := range csvColumnsForType(reflect.TypeOf(example)) {
+		isID := len(column.path) == 1 && strings.EqualFold(column.path[0], "Id")
+		switch {
+		case operation == BulkDelete || operation == BulkHardDelete:
+			if isID {
+				result = append(result, column)
+			}
+		case e.readOnly[column.path[0]]:
+		case isID && (operation == BulkInsert || operation == BulkUpsert):
+		default:
+			result = append(result, column)
+		}
+	}
+	return result
+}
+
+// ingestJob creates an ingest job, uploads data to it, closes it and waits
+// for it to finish.
+func (e *Entity[T]) ingestJob(ctx context.Context, operation BulkOperation, data []byte, config *bulkConfig) (*BulkJob, error) {
+	uri, err := e.instance.JobsURL("ingest")
+	if err != nil {
+		return nil, err
+	}
+	request := BulkJob{
+		Object:              e.name,
+		Operation:           operation,
+		ExternalIDFieldName: config.externalIDField,
+		ContentType:         "CSV",
+		LineEnding:          "LF",
+		ColumnDelimiter:     "COMMA",
+	}
+	var job BulkJob
+	if _, err := e.instance.send(ctx, http.MethodPost, uri.String(), request, &job); err != nil {
+		return nil, err
+	}
+
+	jobURI, err := e.instance.JobsURL("ingest", job.ID)
+	if err != nil {
+		return &job, err
+	}
+	if err := e.uploadJobData(ctx, job.ID, data); err != nil {
+		e.instance.abortJob(ctx, jobURI.String())
+		return &job, err
+	}
+	if _, err := e.instance.send(ctx, http.MethodPatch, jobURI.String(), BulkJob{State: JobStateUploadComplete}, &job); err != nil {
+		e.instance.abortJob(ctx, jobURI.String())
+		return &job, err
+	}
+	result, err := e.instance.waitForJob(ctx, jobURI.String(), config.pollInterval)
+	if err != nil && (result == nil || !isFinished(result.State)) {
+		e.instance.abortJob(ctx, jobURI.String())
+	}
+	if result == nil {
+		return &job, err
+	}
+	return result, err
+}
+
+// uploadJobData uploads the CSV data of an ingest job.
+func (e *Entity[T]) uploadJobData(ctx context.Context, jobID string, data []byte) error {
+	uri, err := e.instance.JobsURL("ingest", jobID, "batches")
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, uri.String(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/csv")
+	req.Header.Set("Accept", "application/json")
+	res, err := e.instance.do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 400 {
+		return errorForResponse(res)
+	}
+	return nil
+}
+
+// ingestResults downloads the results of a finished ingest job into result.
+func (e *Entity[T]) ingestResults(ctx context.Context, jobID string, result *BulkIngestResult[T]) error {
+	err := e.readJobResults(ctx, jobID, "successfulResults", func(d *csvDecoder[T], row []string) error {
+		rec, err := d.decode(row)
+		if err != nil {
+			return err
+		}
+		created, _ := strconv.ParseBool(d.extra(row, "sf__Created"))
+		result.Successful = append(result.Successful, BulkRecordResult[T]{
+			ID:      ID(d.extra(row, "sf__Id")),
+			Created: created,
+			Record:  rec,
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	err = e.readJobResults(ctx, jobID, "failedResults", func(d *csvDecoder[T], row []string) error {
+		rec, err := d.decode(row)
+		if err != nil {
+			return err
+		}
+		result.Failed = append(result.Failed, BulkRecordResult[T]{
+			ID:     ID(d.extra(row, "sf__Id")),
+			Error:  d.extra(row, "sf__Error"),
+			Record: rec,
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return e.readJobResults(ctx, jobID, "unprocessedrecords", func(d *csvDecoder[T], row []string) error {
+		rec, err := d.decode(row)
+		if err != nil {
+			return err
+		}
+		result.Unprocessed = append(result.Unprocessed, rec)
+		return nil
+	})
+}
+
+// readJobResults reads one of the CSV result sets of an ingest job, calling
+// fn for each row.
+func (e *Entity[T]) readJobResults(ctx context.Context, jobID string, kind string, fn func(d *csvDecoder[T], row []string) error) error {
+	uri, err := e.instance.JobsURL("ingest", jobID, kind)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/csv")
+	res, err := e.instance.do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 400 {
+		return errorForResponse(res)
+	}
+	r := csv.NewReader(res.Body)
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	d := newCSVDecoder[T](header)
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(d, row); err != nil {
+			return err
+		}
+	}
+}
+
+// jobAbortTimeout bounds how long aborting a job may take.
+const jobAbortTimeout = 30 * time.Second
+
+// isFinished reports whether a job in state will not change state again.
+func isFinished(state string) bool {
+	return state == JobStateJobComplete || state == JobStateFailed || state == JobStateAborted
+}
+
+// abortJob aborts the job at uri so that it does not stay open, counting
+// against the org's limits, after a failure. It is best effort and is
+// attempted even when ctx is done.
+func (i *Instance) abortJob(ctx context.Context, uri string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), jobAbortTimeout)
+	defer cancel()
+	i.send(ctx, http.MethodPatch, uri, BulkJob{State: JobStateAborted}, nil)
+}
+
+// waitForJob polls the job at uri until it completes, fails or is aborted.
+func (i *Instance) waitForJob(ctx context.Context, uri string, interval time.Duration) (*BulkJob, error) {
+	for {
+		var job BulkJob
+		if _, err := i.send(ctx, http.MethodGet, uri, nil, &job); err != nil {
+			return nil, err
+		}
+		switch job.State {
+		case JobStateJobComplete:
+			return &job, nil
+		case JobStateFailed, JobStateAborted:
+			return &job, fmt.Errorf("job %s %s: %s", job.ID, strings.ToLower(job.State), job.ErrorMessage)
+		}
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return &job, ctx.Err()
+		}
+	}
+}
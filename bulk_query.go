@@ -0,0 +1,131 @@
+package sfdc
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// BulkQuery runs query as a Bulk API 2.0 query job and returns a channel that
+// T are written to, one page of results at a time. The channel is closed when
+// all records have been written. Errors are written to the returned error
+// channel. The query aborts when an error is encountered or ctx is done.
+func (e *Entity[T]) BulkQuery(ctx context.Context, query string, options ...BulkOption) (<-chan []T, <-chan error) {
+	config := newBulkConfig(options)
+	result := make(chan []T)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(result)
+		job, err := e.bulkQueryJob(ctx, query, config)
+		if err != nil {
+			errs <- err
+			return
+		}
+		locator := ""
+		for {
+			records, next, err := e.bulkQueryResults(ctx, job.ID, locator, config)
+			if err != nil {
+				errs <- err
+				return
+			}
+			select {
+			case result <- records:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+			if next == "" || next == "null" {
+				return
+			}
+			locator = next
+		}
+	}()
+	return result, errs
+}
+
+// bulkQueryJob creates a query job and waits for it to finish, aborting it
+// if waiting fails first.
+func (e *Entity[T]) bulkQueryJob(ctx context.Context, query string, config *bulkConfig) (*BulkJob, error) {
+	uri, err := e.instance.JobsURL("query")
+	if err != nil {
+		return nil, err
+	}
+	request := BulkJob{
+		Operation:       "query",
+		Query:           query,
+		ContentType:     "CSV",
+		LineEnding:      "LF",
+		ColumnDelimiter: "COMMA",
+	}
+	var job BulkJob
+	if _, err := e.instance.send(ctx, http.MethodPost, uri.String(), request, &job); err != nil {
+		return nil, err
+	}
+	uri, err = e.instance.JobsURL("query", job.ID)
+	if err != nil {
+		return nil, err
+	}
+	result, err := e.instance.waitForJob(ctx, uri.String(), config.pollInterval)
+	if err != nil && (result == nil || !isFinished(result.State)) {
+		e.instance.abortJob(ctx, uri.String())
+	}
+	return result, err
+}
+
+// bulkQueryResults fetches the page of results at locator, returning the
+// records and the locator of the next page.
+func (e *Entity[T]) bulkQueryResults(ctx context.Context, jobID string, locator string, config *bulkConfig) ([]T, string, error) {
+	uri, err := e.instance.JobsURL("query", jobID, "results")
+	if err != nil {
+		return nil, "", err
+	}
+	q := uri.Query()
+	if locator != "" {
+		q.Set("locator", locator)
+	}
+	if config.maxRecords > 0 {
+		q.Set("maxRecords", fmt.Sprint(config.maxRecords))
+	}
+	uri.RawQuery = q.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri.String(), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Accept", "text/csv")
+	res, err := e.instance.do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 400 {
+		return nil, "", errorForResponse(res)
+	}
+
+	records := []T{}
+	r := csv.NewReader(res.Body)
+	header, err := r.Read()
+	if err == io.EOF {
+		return records, res.Header.Get("Sforce-Locator"), nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	d := newCSVDecoder[T](header)
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		rec, err := d.decode(row)
+		if err != nil {
+			return nil, "", err
+		}
+		records = append(records, rec)
+	}
+	return records, res.Header.Get("Sforce-Locator"), nil
+}
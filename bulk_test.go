@@ -0,0 +1,251 @@
+package sfdc_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/joefitzgerald/sfdc"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+)
+
+func testBulk(t *testing.T, when spec.G, it spec.S) {
+	type Account struct {
+		ID            string `json:"Id,omitempty"`
+		Name          string `json:"Name,omitempty"`
+		NumberOfSites int    `json:"NumberOfSites__c,omitempty"`
+		Ignored       string `json:"Ignored" sfdc:"-"`
+	}
+
+	var (
+		server   *httptest.Server
+		mux      *http.ServeMux
+		accounts *sfdc.Entity[Account]
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		mux = http.NewServeMux()
+		server = httptest.NewServer(mux)
+		instance, err := sfdc.New(sfdc.WithNoAuthentication(), sfdc.WithURL(server.URL))
+		Expect(err).NotTo(HaveOccurred())
+		accounts = sfdc.NewEntity[Account](instance)
+	})
+
+	it.After(func() {
+		server.Close()
+	})
+
+	when("Ingest()", func() {
+		var (
+			uploaded string
+			polls    int
+			patched  []string
+			failing  string
+			cancel   context.CancelFunc
+		)
+
+		it.Before(func() {
+			uploaded = ""
+			polls = 0
+			patched = nil
+			failing = ""
+			mux.HandleFunc("POST /services/data/v54.0/jobs/ingest", func(w http.ResponseWriter, r *http.Request) {
+				var job sfdc.BulkJob
+				Expect(json.NewDecoder(r.Body).Decode(&job)).To(Succeed())
+				Expect(job.Object).To(Equal("Account"))
+				Expect(job.ContentType).To(Equal("CSV"))
+				job.ID = "750000000000001"
+				job.State = sfdc.JobStateOpen
+				json.NewEncoder(w).Encode(job)
+			})
+			mux.HandleFunc("PUT /services/data/v54.0/jobs/ingest/750000000000001/batches", func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.Header.Get("Content-Type")).To(Equal("text/csv"))
+				if failing == "upload" {
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write([]byte(`[{"message":"invalid CSV","errorCode":"INVALIDJOBSTATE"}]`))
+					return
+				}
+				b, err := io.ReadAll(r.Body)
+				Expect(err).NotTo(HaveOccurred())
+				uploaded = string(b)
+				w.WriteHeader(http.StatusCreated)
+			})
+			mux.HandleFunc("PATCH /services/data/v54.0/jobs/ingest/750000000000001", func(w http.ResponseWriter, r *http.Request) {
+				var job sfdc.BulkJob
+				Expect(json.NewDecoder(r.Body).Decode(&job)).To(Succeed())
+				patched = append(patched, job.State)
+				if failing == "close" && job.State == sfdc.JobStateUploadComplete {
+					w.WriteHeader(http.StatusInternalServerError)
+					w.Write([]byte(`[{"message":"unexpected error","errorCode":"UNKNOWN_EXCEPTION"}]`))
+					return
+				}
+				json.NewEncoder(w).Encode(sfdc.BulkJob{ID: "750000000000001", State: job.State})
+			})
+			mux.HandleFunc("GET /services/data/v54.0/jobs/ingest/750000000000001", func(w http.ResponseWriter, r *http.Request) {
+				polls++
+				switch failing {
+				case "poll":
+					w.WriteHeader(http.StatusInternalServerError)
+					w.Write([]byte(`[{"message":"unexpected error","errorCode":"UNKNOWN_EXCEPTION"}]`))
+					return
+				case "cancel":
+					cancel()
+				case "job":
+					json.NewEncoder(w).Encode(sfdc.BulkJob{ID: "750000000000001", State: sfdc.JobStateFailed, ErrorMessage: "invalid field"})
+					return
+				}
+				state := sfdc.JobStateInProgress
+				if polls > 1 {
+					state = sfdc.JobStateJobComplete
+				}
+				json.NewEncoder(w).Encode(sfdc.BulkJob{ID: "750000000000001", State: state})
+			})
+			mux.HandleFunc("GET /services/data/v54.0/jobs/ingest/750000000000001/successfulResults", func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("\"sf__Id\",\"sf__Created\",Name,NumberOfSites__c\n\"001000000000001\",\"true\",\"Acme, Inc.\",3\n"))
+			})
+			mux.HandleFunc("GET /services/data/v54.0/jobs/ingest/750000000000001/failedResults", func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("\"sf__Id\",\"sf__Error\",Name,NumberOfSites__c\n\"\",\"REQUIRED_FIELD_MISSING:Required fields are missing: [Name]:Name --\",\"\",2\n"))
+			})
+			mux.HandleFunc("GET /services/data/v54.0/jobs/ingest/750000000000001/unprocessedrecords", func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("Name,NumberOfSites__c\n"))
+			})
+		})
+
+		it("uploads CSV and returns typed results", func() {
+			result, err := accounts.Ingest(context.Background(), sfdc.BulkInsert, []Account{
+				{ID: "ignored", Name: "Acme, Inc.", NumberOfSites: 3, Ignored: "ignored"},
+				{NumberOfSites: 2},
+			}, sfdc.PollInterval(time.Millisecond))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(uploaded).To(Equal("Name,NumberOfSites__c\n\"Acme, Inc.\",3\n,2\n"))
+			Expect(polls).To(Equal(2))
+			Expect(patched).To(Equal([]string{sfdc.JobStateUploadComplete}))
+			Expect(result.Jobs).To(HaveLen(1))
+			Expect(result.Successful).To(Equal([]sfdc.BulkRecordResult[Account]{{
+				ID:      "001000000000001",
+				Created: true,
+				Record:  Account{Name: "Acme, Inc.", NumberOfSites: 3},
+			}}))
+			Expect(result.Failed).To(HaveLen(1))
+			Expect(result.Failed[0].Error).To(ContainSubstring("REQUIRED_FIELD_MISSING"))
+			Expect(result.Failed[0].Record.NumberOfSites).To(Equal(2))
+			Expect(result.Unprocessed).To(BeEmpty())
+		})
+
+		it("aborts the job when the upload fails", func() {
+			failing = "upload"
+			result, err := accounts.Ingest(context.Background(), sfdc.BulkInsert, []Account{{Name: "Acme"}}, sfdc.PollInterval(time.Millisecond))
+			Expect(err).To(MatchError(ContainSubstring("INVALIDJOBSTATE")))
+			Expect(patched).To(Equal([]string{sfdc.JobStateAborted}))
+			Expect(result.Jobs).To(HaveLen(1))
+		})
+
+		it("aborts the job when it cannot be closed", func() {
+			failing = "close"
+			_, err := accounts.Ingest(context.Background(), sfdc.BulkInsert, []Account{{Name: "Acme"}}, sfdc.PollInterval(time.Millisecond))
+			Expect(err).To(HaveOccurred())
+			Expect(patched).To(Equal([]string{sfdc.JobStateUploadComplete, sfdc.JobStateAborted}))
+		})
+
+		it("aborts the job when polling fails", func() {
+			failing = "poll"
+			_, err := accounts.Ingest(context.Background(), sfdc.BulkInsert, []Account{{Name: "Acme"}}, sfdc.PollInterval(time.Millisecond))
+			Expect(err).To(HaveOccurred())
+			Expect(patched).To(Equal([]string{sfdc.JobStateUploadComplete, sfdc.JobStateAborted}))
+		})
+
+		it("aborts the job when ctx is done while waiting", func() {
+			failing = "cancel"
+			var ctx context.Context
+			ctx, cancel = context.WithCancel(context.Background())
+			defer cancel()
+			_, err := accounts.Ingest(ctx, sfdc.BulkInsert, []Account{{Name: "Acme"}}, sfdc.PollInterval(time.Hour))
+			Expect(err).To(MatchError(context.Canceled))
+			Expect(patched).To(Equal([]string{sfdc.JobStateUploadComplete, sfdc.JobStateAborted}))
+		})
+
+		it("does not abort a job that failed", func() {
+			failing = "job"
+			_, err := accounts.Ingest(context.Background(), sfdc.BulkInsert, []Account{{Name: "Acme"}}, sfdc.PollInterval(time.Millisecond))
+			Expect(err).To(MatchError(ContainSubstring("invalid field")))
+			Expect(patched).To(Equal([]string{sfdc.JobStateUploadComplete}))
+		})
+
+		it("requires an external ID field for upserts", func() {
+			_, err := accounts.Ingest(context.Background(), sfdc.BulkUpsert, []Account{{Name: "Acme"}})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	when("BulkQuery()", func() {
+		it("aborts the job when polling fails", func() {
+			var patched []string
+			mux.HandleFunc("POST /services/data/v54.0/jobs/query", func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(sfdc.BulkJob{ID: "750000000000002", State: sfdc.JobStateUploadComplete})
+			})
+			mux.HandleFunc("GET /services/data/v54.0/jobs/query/750000000000002", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(`[{"message":"unexpected error","errorCode":"UNKNOWN_EXCEPTION"}]`))
+			})
+			mux.HandleFunc("PATCH /services/data/v54.0/jobs/query/750000000000002", func(w http.ResponseWriter, r *http.Request) {
+				var job sfdc.BulkJob
+				Expect(json.NewDecoder(r.Body).Decode(&job)).To(Succeed())
+				patched = append(patched, job.State)
+				json.NewEncoder(w).Encode(sfdc.BulkJob{ID: "750000000000002", State: job.State})
+			})
+
+			records, errs := accounts.BulkQuery(context.Background(), "SELECT Id, Name FROM Account", sfdc.PollInterval(time.Millisecond))
+			Expect(<-errs).To(MatchError(ContainSubstring("UNKNOWN_EXCEPTION")))
+			Eventually(records).Should(BeClosed())
+			Expect(patched).To(Equal([]string{sfdc.JobStateAborted}))
+		})
+
+		it("streams every page of results", func() {
+			mux.HandleFunc("POST /services/data/v54.0/jobs/query", func(w http.ResponseWriter, r *http.Request) {
+				var job sfdc.BulkJob
+				Expect(json.NewDecoder(r.Body).Decode(&job)).To(Succeed())
+				Expect(job.Operation).To(Equal(sfdc.BulkOperation("query")))
+				Expect(job.Query).To(Equal("SELECT Id, Name FROM Account"))
+				json.NewEncoder(w).Encode(sfdc.BulkJob{ID: "750000000000002", State: sfdc.JobStateUploadComplete})
+			})
+			mux.HandleFunc("GET /services/data/v54.0/jobs/query/750000000000002", func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(sfdc.BulkJob{ID: "750000000000002", State: sfdc.JobStateJobComplete})
+			})
+			mux.HandleFunc("GET /services/data/v54.0/jobs/query/750000000000002/results", func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.URL.Query().Get("maxRecords")).To(Equal("1"))
+				switch r.URL.Query().Get("locator") {
+				case "":
+					w.Header().Set("Sforce-Locator", "MTAwMDA")
+					w.Write([]byte("\"Id\",\"Name\"\n\"001000000000001\",\"Acme\"\n"))
+				case "MTAwMDA":
+					w.Header().Set("Sforce-Locator", "null")
+					w.Write([]byte("\"Id\",\"Name\"\n\"001000000000002\",\"Globex\"\n"))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			})
+
+			var result []Account
+			records, errs := accounts.BulkQuery(context.Background(), "SELECT Id, Name FROM Account", sfdc.PollInterval(time.Millisecond), sfdc.MaxRecords(1))
+			for rec := range records {
+				result = append(result, rec...)
+			}
+			var err error
+			select {
+			case err = <-errs:
+			default:
+			}
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal([]Account{
+				{ID: "001000000000001", Name: "Acme"},
+				{ID: "001000000000002", Name: "Globex"},
+			}))
+		})
+	})
+}
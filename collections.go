@@ -0,0 +1,156 @@
+package sfdc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// collectionChunkSize is the maximum number of records the sObject
+// Collections API accepts in a single request.
+const collectionChunkSize = 200
+
+// CollectionOption configures requests that write several records at once.
+type CollectionOption interface {
+	applyToCollection(c *collectionConfig)
+}
+
+type collectionConfig struct {
+	allOrNone bool
+}
+
+type withAllOrNone struct{}
+
+func (w *withAllOrNone) applyToCollection(c *collectionConfig) {
+	c.allOrNone = true
+}
+
+// AllOrNone rolls back every record in a request when any record fails.
+// Collection writes are sent in chunks of 200, so the rollback applies per
+// chunk; once a chunk fails no further chunks are sent.
+func AllOrNone() CollectionOption {
+	return &withAllOrNone{}
+}
+
+type collectionRequest struct {
+	AllOrNone bool     `json:"allOrNone"`
+	Records   []record `json:"records"`
+}
+
+// CreateMany inserts records using the sObject Collections API. The result
+// holds one entry per record, in order.
+func (e *Entity[T]) CreateMany(ctx context.Context, records []T, options ...CollectionOption) ([]SaveResult, error) {
+	uri, err := e.instance.CompositeURL("sobjects")
+	if err != nil {
+		return nil, err
+	}
+	return e.saveMany(ctx, http.MethodPost, uri.String(), records, false, options)
+}
+
+// UpdateMany updates records using the sObject Collections API. Each record
+// must have its Id set. The result holds one entry per record, in order.
+func (e *Entity[T]) UpdateMany(ctx context.Context, records []T, options ...CollectionOption) ([]SaveResult, error) {
+	uri, err := e.instance.CompositeURL("sobjects")
+	if err != nil {
+		return nil, err
+	}
+	return e.saveMany(ctx, http.MethodPatch, uri.String(), records, true, options)
+}
+
+// UpsertMany creates or updates records using the value of an external ID
+// field, which must be set on each record. The result holds one entry per
+// record, in order.
+func (e *Entity[T]) UpsertMany(ctx context.Context, externalIDField string, records []T, options ...CollectionOption) ([]SaveResult, error) {
+	uri, err := e.instance.CompositeURL("sobjects", e.name, externalIDField)
+	if err != nil {
+		return nil, err
+	}
+	return e.saveMany(ctx, http.MethodPatch, uri.String(), records, false, options)
+}
+
+// DeleteMany deletes the records with the given IDs. The result holds one
+// entry per ID, in order.
+func (e *Entity[T]) DeleteMany(ctx context.Context, ids []ID, options ...CollectionOption) ([]SaveResult, error) {
+	config := newCollectionConfig(options)
+	uri, err := e.instance.CompositeURL("sobjects")
+	if err != nil {
+		return nil, err
+	}
+	results := make([]SaveResult, 0, len(ids))
+	for start := 0; start < len(ids); start += collectionChunkSize {
+		end := min(start+collectionChunkSize, len(ids))
+		chunk := make([]string, 0, end-start)
+		for _, id := range ids[start:end] {
+			chunk = append(chunk, string(id))
+		}
+		q := uri.Query()
+		q.Set("ids", strings.Join(chunk, ","))
+		q.Set("allOrNone", fmt.Sprint(config.allOrNone))
+		uri.RawQuery = q.Encode()
+
+		var r []SaveResult
+		if _, err := e.instance.send(ctx, http.MethodDelete, uri.String(), nil, &r); err != nil {
+			return results, err
+		}
+		results = append(results, r...)
+		if err := config.check(r, start); err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+func (e *Entity[T]) saveMany(ctx context.Context, method string, uri string, records []T, keepID bool, options []CollectionOption) ([]SaveResult, error) {
+	config := newCollectionConfig(options)
+	results := make([]SaveResult, 0, len(records))
+	for start := 0; start < len(records); start += collectionChunkSize {
+		end := min(start+collectionChunkSize, len(records))
+		body := collectionRequest{
+			AllOrNone: config.allOrNone,
+			Records:   make([]record, 0, end-start),
+		}
+		for i := range records[start:end] {
+			r, err := newRecord(&records[start+i], e.readOnly, keepID)
+			if err != nil {
+				return results, err
+			}
+			if err := r.setAttributes(map[string]string{"type": e.name}); err != nil {
+				return results, err
+			}
+			body.Records = append(body.Records, r)
+		}
+
+		var r []SaveResult
+		if _, err := e.instance.send(ctx, method, uri, body, &r); err != nil {
+			return results, err
+		}
+		results = append(results, r...)
+		if err := config.check(r, start); err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+func newCollectionConfig(options []CollectionOption) *collectionConfig {
+	config := &collectionConfig{}
+	for i := range options {
+		options[i].applyToCollection(config)
+	}
+	return config
+}
+
+// check stops further chunks from being sent once an all-or-none chunk
+// has been rolled back.
+func (c *collectionConfig) check(results []SaveResult, start int) error {
+	if !c.allOrNone {
+		return nil
+	}
+	for i := range results {
+		if !results[i].Success {
+			return fmt.Errorf("records %d to %d were rolled back: record %d failed", start, start+len(results)-1, start+i)
+		}
+	}
+	return nil
+}
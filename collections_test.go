@@ -0,0 +1,137 @@
+package sfdc_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/joefitzgerald/sfdc"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+)
+
+func testCollections(t *testing.T, when spec.G, it spec.S) {
+	type Account struct {
+		ID   string `json:"Id,omitempty"`
+		Name string `json:"Name,omitempty"`
+	}
+
+	type collectionRequest struct {
+		AllOrNone bool             `json:"allOrNone"`
+		Records   []map[string]any `json:"records"`
+	}
+
+	var (
+		server  *httptest.Server
+		handler func(w http.ResponseWriter, r *http.Request)
+		entity  *sfdc.Entity[Account]
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler(w, r)
+		}))
+		instance, err := sfdc.New(sfdc.WithNoAuthentication(), sfdc.WithURL(server.URL))
+		Expect(err).NotTo(HaveOccurred())
+		entity = sfdc.NewEntity[Account](instance)
+	})
+
+	it.After(func() {
+		server.Close()
+	})
+
+	successes := func(w http.ResponseWriter, n int) {
+		results := make([]sfdc.SaveResult, n)
+		for i := range results {
+			results[i] = sfdc.SaveResult{ID: sfdc.ID(fmt.Sprintf("001%012d", i)), Success: true}
+		}
+		json.NewEncoder(w).Encode(results)
+	}
+
+	when("CreateMany()", func() {
+		it("splits records into chunks of 200", func() {
+			var sizes []int
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.Method).To(Equal(http.MethodPost))
+				Expect(r.URL.Path).To(Equal("/services/data/v54.0/composite/sobjects"))
+				var body collectionRequest
+				Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+				Expect(body.AllOrNone).To(BeFalse())
+				Expect(body.Records[0]["attributes"]).To(Equal(map[string]any{"type": "Account"}))
+				sizes = append(sizes, len(body.Records))
+				successes(w, len(body.Records))
+			}
+			records := make([]Account, 450)
+			results, err := entity.CreateMany(context.Background(), records)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sizes).To(Equal([]int{200, 200, 50}))
+			Expect(results).To(HaveLen(450))
+		})
+
+		it("stops after a failed chunk when using AllOrNone", func() {
+			calls := 0
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				var body collectionRequest
+				Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+				Expect(body.AllOrNone).To(BeTrue())
+				results := make([]sfdc.SaveResult, len(body.Records))
+				results[0].Errors = []sfdc.SaveError{{StatusCode: "REQUIRED_FIELD_MISSING", Message: "Required fields are missing: [Name]"}}
+				json.NewEncoder(w).Encode(results)
+			}
+			records := make([]Account, 250)
+			results, err := entity.CreateMany(context.Background(), records, sfdc.AllOrNone())
+			Expect(err).To(HaveOccurred())
+			Expect(calls).To(Equal(1))
+			Expect(results).To(HaveLen(200))
+			Expect(results[0].Errors[0].StatusCode).To(Equal("REQUIRED_FIELD_MISSING"))
+		})
+	})
+
+	when("UpdateMany()", func() {
+		it("keeps the Id of each record", func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.Method).To(Equal(http.MethodPatch))
+				var body collectionRequest
+				Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+				Expect(body.Records[0]["Id"]).To(Equal("001000000000001"))
+				successes(w, len(body.Records))
+			}
+			results, err := entity.UpdateMany(context.Background(), []Account{{ID: "001000000000001", Name: "Acme"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(1))
+		})
+	})
+
+	when("UpsertMany()", func() {
+		it("uses the external ID field in the URL", func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.Method).To(Equal(http.MethodPatch))
+				Expect(r.URL.Path).To(Equal("/services/data/v54.0/composite/sobjects/Account/External_Id__c"))
+				successes(w, 1)
+			}
+			results, err := entity.UpsertMany(context.Background(), "External_Id__c", []Account{{Name: "Acme"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(1))
+		})
+	})
+
+	when("DeleteMany()", func() {
+		it("passes the IDs in the query string", func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.Method).To(Equal(http.MethodDelete))
+				Expect(r.URL.Query().Get("ids")).To(Equal("001000000000001,001000000000002"))
+				Expect(r.URL.Query().Get("allOrNone")).To(Equal("false"))
+				successes(w, len(strings.Split(r.URL.Query().Get("ids"), ",")))
+			}
+			results, err := entity.DeleteMany(context.Background(), []sfdc.ID{"001000000000001", "001000000000002"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(2))
+		})
+	})
+}
@@ -0,0 +1,257 @@
+package sfdc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+)
+
+// compositeLimit is the maximum number of subrequests in a composite request.
+const compositeLimit = 25
+
+// Composite batches subrequests into a single call to the composite resource.
+// Later subrequests can refer to the results of earlier ones using Ref.
+// Subrequests are added using the Entity methods ending in In, for example
+// CreateIn and QueryIn.
+type Composite struct {
+	subrequestList
+	instance  *Instance
+	allOrNone bool
+}
+
+// Subrequests is implemented by the request types that subrequests can be
+// added to: Composite and Graph.
+type Subrequests interface {
+	subrequests() *subrequestList
+}
+
+// subrequestList holds subrequests and how to decode their responses.
+type subrequestList struct {
+	requests []compositeSubrequest
+	results  map[string]func(status int, body json.RawMessage) error
+	err      error
+}
+
+func (l *subrequestList) subrequests() *subrequestList {
+	return l
+}
+
+type compositeSubrequest struct {
+	Method      string `json:"method"`
+	URL         string `json:"url"`
+	ReferenceID string `json:"referenceId"`
+	Body        any    `json:"body,omitempty"`
+}
+
+type compositeRequest struct {
+	AllOrNone        bool                  `json:"allOrNone"`
+	CompositeRequest []compositeSubrequest `json:"compositeRequest"`
+}
+
+type compositeResponse struct {
+	CompositeResponse []compositeSubresponse `json:"compositeResponse"`
+}
+
+type compositeSubresponse struct {
+	Body           json.RawMessage `json:"body"`
+	HTTPStatusCode int             `json:"httpStatusCode"`
+	ReferenceID    string          `json:"referenceId"`
+}
+
+// Subrequest holds the outcome of a single subrequest once the Composite it
+// belongs to has been executed.
+type Subrequest[T any] struct {
+	ReferenceID string
+	StatusCode  int
+	Result      T
+	Err         error
+}
+
+// Ref returns a reference to a field of this subrequest's result, such as
+// Ref("id"), for use in the URL or body of a later subrequest.
+func (s *Subrequest[T]) Ref(field string) string {
+	return Reference(s.ReferenceID, field)
+}
+
+// Reference returns a reference to a field of the result of the subrequest
+// with the given reference ID, e.g. "@{NewAccount.id}".
+func Reference(referenceID string, field string) string {
+	return fmt.Sprintf("@{%s.%s}", referenceID, field)
+}
+
+// NewComposite creates an empty composite request. AllOrNone rolls back
+// every subrequest when any of them fails.
+func (i *Instance) NewComposite(options ...CollectionOption) *Composite {
+	config := newCollectionConfig(options)
+	return &Composite{
+		instance:  i,
+		allOrNone: config.allOrNone,
+	}
+}
+
+// Execute sends the subrequests and populates each Subrequest. The returned
+// error joins the errors of any subrequests that failed.
+func (c *Composite) Execute(ctx context.Context) error {
+	if c.err != nil {
+		return c.err
+	}
+	if len(c.requests) > compositeLimit {
+		return fmt.Errorf("composite requests are limited to %d subrequests, got %d", compositeLimit, len(c.requests))
+	}
+	uri, err := c.instance.CompositeURL()
+	if err != nil {
+		return err
+	}
+	body := compositeRequest{
+		AllOrNone:        c.allOrNone,
+		CompositeRequest: c.requests,
+	}
+	var r compositeResponse
+	if _, err := c.instance.send(ctx, http.MethodPost, uri.String(), body, &r); err != nil {
+		return err
+	}
+	return c.resolve(r.CompositeResponse)
+}
+
+// resolve decodes each subresponse into its Subrequest and joins the errors
+// of those that failed.
+func (l *subrequestList) resolve(responses []compositeSubresponse) error {
+	var errs []error
+	for _, response := range responses {
+		result, ok := l.results[response.ReferenceID]
+		if !ok {
+			continue
+		}
+		if err := result(response.HTTPStatusCode, response.Body); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// addSubrequest appends a subrequest whose response is decoded into a T.
+// Errors building the subrequest are reported when the request is executed.
+func addSubrequest[T any](s Subrequests, method string, uri *url.URL, err error, referenceID string, body any) *Subrequest[T] {
+	return addDecodedSubrequest(s, method, uri, err, referenceID, body, func(b json.RawMessage, out *T) error {
+		return json.Unmarshal(b, out)
+	})
+}
+
+// addDecodedSubrequest appends a subrequest whose response is decoded into a
+// T by decode.
+func addDecodedSubrequest[T any](s Subrequests, method string, uri *url.URL, err error, referenceID string, body any, decode func(b json.RawMessage, out *T) error) *Subrequest[T] {
+	c := s.subrequests()
+	result := &Subrequest[T]{ReferenceID: referenceID}
+	if err != nil {
+		c.err = errors.Join(c.err, fmt.Errorf("%s: %w", referenceID, err))
+		return result
+	}
+	if c.results == nil {
+		c.results = map[string]func(status int, body json.RawMessage) error{}
+	}
+	c.requests = append(c.requests, compositeSubrequest{
+		Method:      method,
+		URL:         subrequestURL(uri),
+		ReferenceID: referenceID,
+		Body:        body,
+	})
+	c.results[referenceID] = func(status int, body json.RawMessage) error {
+		result.StatusCode = status
+		err := decodeSubresponse(status, body, func(b json.RawMessage) error {
+			return decode(b, &result.Result)
+		})
+		if err != nil {
+			result.Err = fmt.Errorf("%s: %w", referenceID, err)
+		}
+		return result.Err
+	}
+	return result
+}
+
+// escapedReference matches a reference that was path escaped when it was
+// used as a URL segment.
+var escapedReference = regexp.MustCompile(`@%7B(.*?)%7D`)
+
+// subrequestURL returns the path and query of uri with any references
+// restored, as Salesforce only resolves references written as @{...}.
+func subrequestURL(uri *url.URL) string {
+	return escapedReference.ReplaceAllStringFunc(uri.RequestURI(), func(s string) string {
+		reference, err := url.PathUnescape(s)
+		if err != nil {
+			return s
+		}
+		return reference
+	})
+}
+
+func decodeSubresponse(status int, body json.RawMessage, decode func(b json.RawMessage) error) error {
+	if status >= 400 {
+		return parseAPIError(status, body)
+	}
+	if len(body) == 0 || string(body) == "null" {
+		return nil
+	}
+	return decode(body)
+}
+
+// CreateIn adds a subrequest to c that inserts record.
+func (e *Entity[T]) CreateIn(c Subrequests, referenceID string, record *T) *Subrequest[SaveResult] {
+	uri, err := e.instance.SObjectURL(e.name)
+	body, bodyErr := newRecord(record, e.readOnly, false)
+	return addSubrequest[SaveResult](c, http.MethodPost, uri, errors.Join(err, bodyErr), referenceID, body)
+}
+
+// GetIn adds a subrequest to c that fetches the record with the given ID.
+func (e *Entity[T]) GetIn(c Subrequests, referenceID string, id ID) *Subrequest[T] {
+	uri, err := e.instance.SObjectURL(e.name, string(id))
+	return addSubrequest[T](c, http.MethodGet, uri, err, referenceID, nil)
+}
+
+// UpdateIn adds a subrequest to c that writes record to the record with the
+// given ID.
+func (e *Entity[T]) UpdateIn(c Subrequests, referenceID string, id ID, record *T) *Subrequest[struct{}] {
+	uri, err := e.instance.SObjectURL(e.name, string(id))
+	body, bodyErr := newRecord(record, e.readOnly, false)
+	return addSubrequest[struct{}](c, http.MethodPatch, uri, errors.Join(err, bodyErr), referenceID, body)
+}
+
+// DeleteIn adds a subrequest to c that deletes the record with the given ID.
+func (e *Entity[T]) DeleteIn(c Subrequests, referenceID string, id ID) *Subrequest[struct{}] {
+	uri, err := e.instance.SObjectURL(e.name, string(id))
+	return addSubrequest[struct{}](c, http.MethodDelete, uri, err, referenceID, nil)
+}
+
+// QueryIn adds a subrequest to c that runs query. Only the first page of
+// results, and of the records of each child relationship, is returned.
+func (e *Entity[T]) QueryIn(c Subrequests, referenceID string, query string, options ...QueryOption) *Subrequest[QueryResponse[T]] {
+	var uri *url.URL
+	reqURI, err := e.queryURI(query, e.newQueryConfig(options))
+	if err == nil {
+		uri, err = url.Parse(reqURI)
+	}
+	return addDecodedSubrequest(c, http.MethodGet, uri, err, referenceID, nil, e.decodeQueryResponse)
+}
+
+// decodeQueryResponse decodes a page of query results like fetchPage,
+// without fetching further pages of child records.
+func (e *Entity[T]) decodeQueryResponse(b json.RawMessage, out *QueryResponse[T]) error {
+	var r QueryResponse[json.RawMessage]
+	if err := json.Unmarshal(b, &r); err != nil {
+		return err
+	}
+	records, err := e.decodeRecords(r.Records, nil)
+	if err != nil {
+		return err
+	}
+	*out = QueryResponse[T]{
+		Done:           r.Done,
+		NextRecordsURL: r.NextRecordsURL,
+		Records:        records,
+		TotalSize:      r.TotalSize,
+	}
+	return nil
+}
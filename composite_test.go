@@ -0,0 +1,135 @@
+package sfdc_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/joefitzgerald/sfdc"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+)
+
+func testComposite(t *testing.T, when spec.G, it spec.S) {
+	type Account struct {
+		ID   string `json:"Id,omitempty"`
+		Name string `json:"Name,omitempty"`
+	}
+
+	type Contact struct {
+		ID        string `json:"Id,omitempty"`
+		AccountID string `json:"AccountId,omitempty"`
+		LastName  string `json:"LastName,omitempty"`
+	}
+
+	var (
+		server   *httptest.Server
+		handler  func(w http.ResponseWriter, r *http.Request)
+		instance *sfdc.Instance
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler(w, r)
+		}))
+		var err error
+		instance, err = sfdc.New(sfdc.WithNoAuthentication(), sfdc.WithURL(server.URL))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	it.After(func() {
+		server.Close()
+	})
+
+	it("sends subrequests with references and decodes each subresponse", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.Method).To(Equal(http.MethodPost))
+			Expect(r.URL.Path).To(Equal("/services/data/v54.0/composite"))
+			var body struct {
+				AllOrNone        bool `json:"allOrNone"`
+				CompositeRequest []struct {
+					Method      string         `json:"method"`
+					URL         string         `json:"url"`
+					ReferenceID string         `json:"referenceId"`
+					Body        map[string]any `json:"body"`
+				} `json:"compositeRequest"`
+			}
+			Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+			Expect(body.AllOrNone).To(BeTrue())
+			Expect(body.CompositeRequest).To(HaveLen(3))
+			Expect(body.CompositeRequest[0].URL).To(Equal("/services/data/v54.0/sobjects/Account"))
+			Expect(body.CompositeRequest[1].Body["AccountId"]).To(Equal("@{NewAccount.id}"))
+			Expect(body.CompositeRequest[2].Method).To(Equal(http.MethodGet))
+			Expect(body.CompositeRequest[2].URL).To(Equal("/services/data/v54.0/sobjects/Account/@{NewAccount.id}"))
+			w.Write([]byte(`{"compositeResponse":[
+				{"body":{"id":"001000000000001","success":true,"errors":[]},"httpStatusCode":201,"referenceId":"NewAccount"},
+				{"body":{"id":"003000000000001","success":true,"errors":[]},"httpStatusCode":201,"referenceId":"NewContact"},
+				{"body":{"Id":"001000000000001","Name":"Acme"},"httpStatusCode":200,"referenceId":"Account"}
+			]}`))
+		}
+
+		accounts := sfdc.NewEntity[Account](instance)
+		contacts := sfdc.NewEntity[Contact](instance)
+		composite := instance.NewComposite(sfdc.AllOrNone())
+		account := accounts.CreateIn(composite, "NewAccount", &Account{Name: "Acme"})
+		contact := contacts.CreateIn(composite, "NewContact", &Contact{AccountID: account.Ref("id"), LastName: "Smith"})
+		fetched := accounts.GetIn(composite, "Account", sfdc.ID(account.Ref("id")))
+
+		Expect(composite.Execute(context.Background())).To(Succeed())
+		Expect(account.StatusCode).To(Equal(http.StatusCreated))
+		Expect(account.Result.ID).To(Equal(sfdc.ID("001000000000001")))
+		Expect(contact.Result.ID).To(Equal(sfdc.ID("003000000000001")))
+		Expect(fetched.Result.Name).To(Equal("Acme"))
+	})
+
+	it("reports subrequest errors", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"compositeResponse":[
+				{"body":[{"message":"Required fields are missing: [Name]","errorCode":"REQUIRED_FIELD_MISSING"}],"httpStatusCode":400,"referenceId":"NewAccount"}
+			]}`))
+		}
+
+		composite := instance.NewComposite()
+		account := sfdc.NewEntity[Account](instance).CreateIn(composite, "NewAccount", &Account{})
+		err := composite.Execute(context.Background())
+		Expect(err).To(HaveOccurred())
+		Expect(account.Err).To(HaveOccurred())
+		Expect(account.Err.Error()).To(ContainSubstring("REQUIRED_FIELD_MISSING"))
+	})
+
+	it("decodes the child records of a query", func() {
+		type AccountWithContacts struct {
+			ID       string    `json:"Id"`
+			Contacts []Contact `json:"contacts" sfdc:"Contacts"`
+		}
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"compositeResponse":[
+				{"body":{"totalSize":1,"done":true,"records":[
+					{"Id":"001000000000001","Contacts":{"totalSize":3,"done":false,"nextRecordsUrl":"/services/data/v54.0/query/01g-2","records":[{"Id":"003000000000001"},{"Id":"003000000000002"}]}}
+				]},"httpStatusCode":200,"referenceId":"Accounts"}
+			]}`))
+		}
+
+		composite := instance.NewComposite()
+		accounts := sfdc.NewEntity[AccountWithContacts](instance)
+		accounts.SetName("Account")
+		result := accounts.QueryIn(composite, "Accounts", accounts.Select().String())
+		Expect(composite.Execute(context.Background())).To(Succeed())
+		Expect(result.Result.Records).To(Equal([]AccountWithContacts{{
+			ID:       "001000000000001",
+			Contacts: []Contact{{ID: "003000000000001"}, {ID: "003000000000002"}},
+		}}))
+	})
+
+	it("rejects more than 25 subrequests", func() {
+		composite := instance.NewComposite()
+		accounts := sfdc.NewEntity[Account](instance)
+		for range 26 {
+			accounts.QueryIn(composite, "Query", "SELECT Id FROM Account")
+		}
+		Expect(composite.Execute(context.Background())).NotTo(Succeed())
+	})
+}
@@ -0,0 +1,168 @@
+package sfdc
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// csvNull is the value the Bulk API uses to set a field to null.
+const csvNull = "#N/A"
+
+// csvColumn maps a CSV column to a field of a record.
+type csvColumn struct {
+	// name is the Salesforce field name used in the CSV header, such as Name
+	// or Owner.Name.
+	name string
+	// path holds the JSON keys leading to the field; it has more than one
+	// element for fields of parent relationships.
+	path []string
+	typ  reflect.Type
+}
+
+// csvColumnsForType returns the columns for the plain fields of t and its
+// parent relationships, skipping expressions, subqueries and child
+// relationships.
+func csvColumnsForType(t reflect.Type) []csvColumn {
+	return csvColumns(t, "", nil, defaultRelationshipDepth)
+}
+
+func csvColumns(t reflect.Type, prefix string, path []string, depth int) []csvColumn {
+	result := []csvColumn{}
+	for _, field := range deepFields(t) {
+		name, ok := fieldTarget(field)
+		if !ok || strings.ContainsAny(name, "() ") || isChildRelationship(field) {
+			continue
+		}
+		key, _ := jsonName(field)
+		fieldPath := append(append([]string{}, path...), key)
+		if parent, ok := parentRelationship(field); ok {
+			if depth > 0 {
+				result = append(result, csvColumns(parent, prefix+name+".", fieldPath, depth-1)...)
+			}
+			continue
+		}
+		result = append(result, csvColumn{name: prefix + name, path: fieldPath, typ: field.Type})
+	}
+	return result
+}
+
+// csvHeader returns the header row for columns.
+func csvHeader(columns []csvColumn) []string {
+	result := make([]string, 0, len(columns))
+	for _, column := range columns {
+		result = append(result, column.name)
+	}
+	return result
+}
+
+// csvRow returns the CSV values of columns in r. Fields missing from r are
+// left empty and null fields are written as #N/A.
+func csvRow(columns []csvColumn, r record) []string {
+	result := make([]string, 0, len(columns))
+	for _, column := range columns {
+		raw, ok := r[column.path[0]]
+		switch {
+		case !ok:
+			result = append(result, "")
+		case string(raw) == "null":
+			result = append(result, csvNull)
+		default:
+			var s string
+			if err := json.Unmarshal(raw, &s); err == nil {
+				result = append(result, s)
+			} else {
+				result = append(result, string(raw))
+			}
+		}
+	}
+	return result
+}
+
+// csvDecoder decodes CSV rows into T by way of its JSON encoding.
+type csvDecoder[T any] struct {
+	// columns holds the column for each index in the header, or nil when the
+	// header names a field T does not have.
+	columns []*csvColumn
+	// extras holds the index of header names T does not have, such as the
+	// sf__Id and sf__Error columns of ingest results.
+	extras map[string]int
+}
+
+func newCSVDecoder[T any](header []string) *csvDecoder[T] {
+	var example T
+	known := csvColumnsForType(reflect.TypeOf(example))
+	result := &csvDecoder[T]{
+		columns: make([]*csvColumn, len(header)),
+		extras:  map[string]int{},
+	}
+	for i, name := range header {
+		for j := range known {
+			if strings.EqualFold(known[j].name, name) || strings.EqualFold(strings.Join(known[j].path, "."), name) {
+				result.columns[i] = &known[j]
+				break
+			}
+		}
+		if result.columns[i] == nil {
+			result.extras[name] = i
+		}
+	}
+	return result
+}
+
+// extra returns the value of a header column that T does not have.
+func (d *csvDecoder[T]) extra(row []string, name string) string {
+	i, ok := d.extras[name]
+	if !ok || i >= len(row) {
+		return ""
+	}
+	return row[i]
+}
+
+func (d *csvDecoder[T]) decode(row []string) (T, error) {
+	var result T
+	r := map[string]any{}
+	for i, value := range row {
+		if i >= len(d.columns) || d.columns[i] == nil || value == "" {
+			continue
+		}
+		// Build the nested objects of parent relationships along the way.
+		path := d.columns[i].path
+		parent := r
+		for _, key := range path[:len(path)-1] {
+			child, ok := parent[key].(map[string]any)
+			if !ok {
+				child = map[string]any{}
+				parent[key] = child
+			}
+			parent = child
+		}
+		parent[path[len(path)-1]] = csvValue(d.columns[i].typ, value)
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		return result, err
+	}
+	err = json.Unmarshal(b, &result)
+	return result, err
+}
+
+// csvValue converts a CSV value to JSON suitable for decoding into typ.
+func csvValue(typ reflect.Type, value string) json.RawMessage {
+	if value == csvNull {
+		return json.RawMessage("null")
+	}
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if json.Valid([]byte(value)) {
+			return json.RawMessage(value)
+		}
+	}
+	b, _ := json.Marshal(value)
+	return b
+}
@@ -1,6 +1,45 @@
 package sfdc
 
+import (
+	"encoding/json"
+	"time"
+)
+
 const (
 	// DateTimeLayout is used to convert SFDC DateTime strings correctly
 	DateTimeLayout = "2006-01-02T15:04:05.000Z"
+	// DateLayout is used to convert SFDC Date strings correctly
+	DateLayout = "2006-01-02"
 )
+
+// Date is a calendar date, such as the value of an SFDC Date field. It is
+// written to SOQL as a date literal rather than a DateTime.
+type Date time.Time
+
+func (d Date) String() string {
+	return time.Time(d).Format(DateLayout)
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	if time.Time(d).IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(d.String())
+}
+
+func (d *Date) UnmarshalJSON(b []byte) error {
+	var s *string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	if s == nil || *s == "" {
+		*d = Date{}
+		return nil
+	}
+	t, err := time.Parse(DateLayout, *s)
+	if err != nil {
+		return err
+	}
+	*d = Date(t)
+	return nil
+}
@@ -2,19 +2,28 @@ package sfdc
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"iter"
 	"net/http"
 	"reflect"
+	"strings"
 	"time"
 	"unicode/utf8"
 )
 
+// ID is a Salesforce record ID.
+type ID string
+
 type Entity[T any] struct {
 	instance     *Instance
 	name         string
 	allFields    string
 	taggedFields string
+	readOnly     map[string]bool
+	children     []childRelationship
+	polymorphic  []polymorphicField
+	// includeDeleted selects the queryAll resource by default.
+	includeDeleted bool
 }
 
 func NewEntity[T any](instance *Instance) *Entity[T] {
@@ -24,7 +33,11 @@ func NewEntity[T any](instance *Instance) *Entity[T] {
 	name := typ.Name()
 	result.instance = instance
 	result.name = name
-	result.taggedFields = fieldsForType(typ)
+	result.taggedFields = fieldsForType(typ, defaultRelationshipDepth)
+	result.readOnly = readOnlyFieldsForType(typ)
+	result.children = childRelationshipsForType(typ)
+	result.polymorphic = polymorphicFieldsForType(typ)
+	result.includeDeleted = true
 	return &result
 }
 
@@ -32,10 +45,29 @@ func (e *Entity[T]) SetName(name string) {
 	e.name = name
 }
 
+// SetIncludeDeleted sets whether queries use the queryAll resource, which
+// returns deleted and archived records, rather than the query resource. The
+// default is true; use IncludeDeleted or ExcludeDeleted to override it for a
+// single query.
+func (e *Entity[T]) SetIncludeDeleted(includeDeleted bool) {
+	e.includeDeleted = includeDeleted
+}
+
+// SetRelationshipDepth sets how many levels of parent relationships, held in
+// nested structs, are selected by TaggedFields. The default is 5, the most
+// SOQL allows; 0 selects no relationship fields.
+func (e *Entity[T]) SetRelationshipDepth(depth int) {
+	var example T
+	e.taggedFields = fieldsForType(reflect.TypeOf(example), depth)
+}
+
 func (e *Entity[T]) TaggedFields() string {
 	return e.taggedFields
 }
 
+// BuildQuery returns a query for fields, with constraints as the WHERE clause
+// when it is not empty. The constraints are used as is; use FormatSOQL to
+// include values in them safely, or Select to build the whole query.
 func (e *Entity[T]) BuildQuery(fields string, constraints string) string {
 	query := fmt.Sprintf("SELECT %v FROM %s", fields, e.name)
 	if utf8.RuneCountInString(constraints) > 0 {
@@ -44,107 +76,229 @@ func (e *Entity[T]) BuildQuery(fields string, constraints string) string {
 	return query
 }
 
-func (e *Entity[T]) Query(ctx context.Context, query string) ([]T, error) {
-	var r QueryResponse[T]
+// Query runs query and returns all of its records.
+func (e *Entity[T]) Query(ctx context.Context, query string, options ...QueryOption) ([]T, error) {
 	results := []T{}
-	uri, err := e.instance.QueryAllURL()
-	if err != nil {
-		return nil, err
-	}
-	q := uri.Query()
-	q.Set("q", query)
-	uri.RawQuery = q.Encode()
-	reqURI := uri.String()
-	for !r.Done {
-		if r.NextRecordsURL != "" {
-			reqURI = fmt.Sprintf("%v%v", e.instance.url, r.NextRecordsURL)
-		}
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURI, nil)
+	for records, err := range e.Pages(ctx, query, options...) {
 		if err != nil {
 			return nil, err
 		}
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Accept", "application/json")
+		results = append(results, records...)
+	}
+	return results, nil
+}
+
+// All returns an iterator over the records of query, fetching each page of
+// results as it is reached. Breaking out of the loop stops further pages from
+// being fetched. An error ends the iteration:
+//
+//	for account, err := range accounts.All(ctx, query) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func (e *Entity[T]) All(ctx context.Context, query string, options ...QueryOption) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for records, err := range e.Pages(ctx, query, options...) {
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, record := range records {
+				if !yield(record, nil) {
+					return
+				}
+			}
+		}
+	}
+}
 
-		res, err := e.instance.client.Do(req)
-		if err != nil {
-			return nil, err
+// Pages returns an iterator over the pages of records of query. Each page is
+// fetched as it is reached, and breaking out of the loop stops further pages
+// from being fetched. An error ends the iteration.
+func (e *Entity[T]) Pages(ctx context.Context, query string, options ...QueryOption) iter.Seq2[[]T, error] {
+	return func(yield func([]T, error) bool) {
+		for page, err := range e.Responses(ctx, query, options...) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(page.Records, nil) {
+				return
+			}
 		}
-		defer res.Body.Close()
-		if res.StatusCode >= 400 {
-			return nil, errorForResponse(res.Body)
+	}
+}
+
+// Responses returns an iterator over the pages of query like Pages, yielding
+// each response so that its Cursor can be saved as a checkpoint:
+//
+//	for page, err := range accounts.Responses(ctx, query) {
+//		if err != nil {
+//			return err
+//		}
+//		process(page.Records)
+//		save(page.Cursor())
+//	}
+func (e *Entity[T]) Responses(ctx context.Context, query string, options ...QueryOption) iter.Seq2[*QueryResponse[T], error] {
+	return func(yield func(*QueryResponse[T], error) bool) {
+		config := e.newQueryConfig(options)
+		reqURI, err := e.queryURI(query, config)
+		if err != nil {
+			yield(nil, err)
+			return
 		}
-		r.Records = nil
-		if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
-			return nil, err
+		e.responses(ctx, reqURI, config, yield)
+	}
+}
+
+// Resume returns an iterator over the pages of a query that follow cursor,
+// continuing a query whose progress was saved with QueryResponse.Cursor. An
+// empty cursor yields no pages.
+func (e *Entity[T]) Resume(ctx context.Context, cursor Cursor, options ...QueryOption) iter.Seq2[*QueryResponse[T], error] {
+	return func(yield func(*QueryResponse[T], error) bool) {
+		if cursor == "" {
+			return
 		}
-		results = append(results, r.Records...)
+		e.responses(ctx, e.instance.nextRecordsURL(string(cursor)), e.newQueryConfig(options), yield)
 	}
+}
 
-	return results, nil
+// responses fetches the page at uri and each page after it, passing them to
+// yield until it returns false.
+func (e *Entity[T]) responses(ctx context.Context, uri string, config *queryConfig, yield func(*QueryResponse[T], error) bool) {
+	for {
+		page, err := e.fetchPage(ctx, uri, config)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		if !yield(page, nil) || page.Cursor() == "" {
+			return
+		}
+		uri = e.instance.nextRecordsURL(page.NextRecordsURL)
+	}
 }
 
 // QueryAsync returns a channel that T are written to.
 // The channel is closed when all records have been written.
 // Errors are written to the returned error channel.
-// The query aborts when an error is encountered.
-func (e *Entity[T]) QueryAsync(ctx context.Context, query string) (<-chan []T, <-chan error) {
+// The query aborts when an error is encountered or ctx is done; cancel ctx
+// to stop reading early. All and Pages are simpler to use in most cases.
+func (e *Entity[T]) QueryAsync(ctx context.Context, query string, options ...QueryOption) (<-chan []T, <-chan error) {
 	result := make(chan []T)
 	errs := make(chan error, 1)
-	uri, err := e.instance.QueryAllURL()
-	if err != nil {
-		errs <- err
-		close(result)
-		return result, errs
-	}
 	go func() {
-		var r QueryResponse[T]
-
-		q := uri.Query()
-		q.Set("q", query)
-		uri.RawQuery = q.Encode()
-		reqURI := uri.String()
-		for !r.Done {
-			if r.NextRecordsURL != "" {
-				reqURI = fmt.Sprintf("%v%v", e.instance.url, r.NextRecordsURL)
-			}
-			req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURI, nil)
+		defer close(result)
+		for records, err := range e.Pages(ctx, query, options...) {
 			if err != nil {
 				errs <- err
-				break
+				return
 			}
-			req.Header.Set("Content-Type", "application/json")
-			req.Header.Set("Accept", "application/json")
-
-			res, err := e.instance.client.Do(req)
-			if err != nil {
-				errs <- err
-				break
-			}
-			defer res.Body.Close()
-			if res.StatusCode >= 400 {
-				errs <- errorForResponse(res.Body)
-				break
-			}
-			r.Records = nil
-			if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
-				errs <- err
-				break
+			select {
+			case result <- records:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
 			}
-			result <- r.Records
 		}
-		close(result)
 	}()
-
 	return result, errs
 }
 
 // List finds all T objects.
-func (e *Entity[T]) List(ctx context.Context) (<-chan []T, <-chan error) {
-	return e.QueryAsync(ctx, "")
+func (e *Entity[T]) List(ctx context.Context, options ...QueryOption) (<-chan []T, <-chan error) {
+	return e.QueryAsync(ctx, e.Select().String(), options...)
 }
 
 // ListModifiedSince finds all T objects modified since some point in time.
-func (e *Entity[T]) ListModifiedSince(ctx context.Context, since time.Time) (<-chan []T, <-chan error) {
-	return e.QueryAsync(ctx, fmt.Sprintf("LastModifiedDate > %s", since.Format(DateTimeLayout)))
+func (e *Entity[T]) ListModifiedSince(ctx context.Context, since time.Time, options ...QueryOption) (<-chan []T, <-chan error) {
+	return e.QueryAsync(ctx, e.Select().Where(Gt("LastModifiedDate", since)).String(), options...)
+}
+
+// Create inserts record and returns the ID of the new record.
+func (e *Entity[T]) Create(ctx context.Context, record *T) (ID, error) {
+	uri, err := e.instance.SObjectURL(e.name)
+	if err != nil {
+		return "", err
+	}
+	body, err := newRecord(record, e.readOnly, false)
+	if err != nil {
+		return "", err
+	}
+	var result struct {
+		ID ID `json:"id"`
+	}
+	if _, err := e.instance.send(ctx, http.MethodPost, uri.String(), body, &result); err != nil {
+		return "", err
+	}
+	return result.ID, nil
+}
+
+// Get fetches the record with the given ID.
+func (e *Entity[T]) Get(ctx context.Context, id ID) (*T, error) {
+	uri, err := e.instance.SObjectURL(e.name, string(id))
+	if err != nil {
+		return nil, err
+	}
+	var result T
+	if _, err := e.instance.send(ctx, http.MethodGet, uri.String(), nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Update writes record to the record with the given ID. Fields tagged
+// sfdc:",readonly", relationships and zero time.Time and Date fields are not
+// written. Use omitempty on other fields that should not be cleared when they
+// are unset.
+func (e *Entity[T]) Update(ctx context.Context, id ID, record *T) error {
+	uri, err := e.instance.SObjectURL(e.name, string(id))
+	if err != nil {
+		return err
+	}
+	body, err := newRecord(record, e.readOnly, false)
+	if err != nil {
+		return err
+	}
+	_, err = e.instance.send(ctx, http.MethodPatch, uri.String(), body, nil)
+	return err
+}
+
+// Delete removes the record with the given ID.
+func (e *Entity[T]) Delete(ctx context.Context, id ID) error {
+	uri, err := e.instance.SObjectURL(e.name, string(id))
+	if err != nil {
+		return err
+	}
+	_, err = e.instance.send(ctx, http.MethodDelete, uri.String(), nil, nil)
+	return err
+}
+
+// Upsert creates or updates record using the value of an external ID field.
+// The result reports whether a new record was created.
+func (e *Entity[T]) Upsert(ctx context.Context, externalIDField string, value string, record *T) (*SaveResult, error) {
+	uri, err := e.instance.SObjectURL(e.name, externalIDField, value)
+	if err != nil {
+		return nil, err
+	}
+	body, err := newRecord(record, e.readOnly, false)
+	if err != nil {
+		return nil, err
+	}
+	for key := range body {
+		if strings.EqualFold(key, externalIDField) {
+			delete(body, key)
+		}
+	}
+	var result SaveResult
+	status, err := e.instance.send(ctx, http.MethodPatch, uri.String(), body, &result)
+	if err != nil {
+		return nil, err
+	}
+	result.Success = true
+	result.Created = status == http.StatusCreated
+	return &result, nil
 }
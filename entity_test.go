@@ -2,10 +2,14 @@ package sfdc_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/joefitzgerald/sfdc"
 	. "github.com/onsi/gomega"
@@ -46,6 +50,25 @@ func testEntity(t *testing.T, when spec.G, it spec.S) {
 		})
 	})
 
+	when("decoding a Date", func() {
+		it("parses dates and treats null and empty strings as zero", func() {
+			var d struct {
+				CloseDate sfdc.Date `json:"CloseDate"`
+				Empty     sfdc.Date `json:"Empty"`
+				Null      sfdc.Date `json:"Null"`
+			}
+			Expect(json.Unmarshal([]byte(`{"CloseDate":"2024-03-31","Empty":"","Null":null}`), &d)).To(Succeed())
+			Expect(d.CloseDate.String()).To(Equal("2024-03-31"))
+			Expect(time.Time(d.Empty).IsZero()).To(BeTrue())
+			Expect(time.Time(d.Null).IsZero()).To(BeTrue())
+		})
+
+		it("rejects values that are not strings", func() {
+			var d sfdc.Date
+			Expect(json.Unmarshal([]byte(`123`), &d)).NotTo(Succeed())
+		})
+	})
+
 	when("using an invalid instance url", func() {
 		it.Before(func() {
 			var err error
@@ -143,6 +166,268 @@ func testEntity(t *testing.T, when spec.G, it spec.S) {
 					Expect(result).NotTo(BeNil())
 					Expect(result).To(HaveLen(2))
 				})
+
+				it("uses the query resource when deleted records are excluded", func() {
+					handler = func(w http.ResponseWriter, r *http.Request) {
+						Expect(r.URL.Path).To(Equal("/services/data/v54.0/query"))
+						Expect(r.URL.Query().Get("q")).To(Equal("SELECT Id FROM Account"))
+						w.Write([]byte(`{"records":[], "done":true}`))
+					}
+					_, err := entity.Query(context.Background(), "SELECT Id FROM Account", sfdc.ExcludeDeleted())
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				it("uses the entity's setting unless a query overrides it", func() {
+					var paths []string
+					handler = func(w http.ResponseWriter, r *http.Request) {
+						paths = append(paths, r.URL.Path)
+						w.Write([]byte(`{"records":[], "done":true}`))
+					}
+					entity.SetIncludeDeleted(false)
+					_, err := entity.Query(context.Background(), "SELECT Id FROM Account")
+					Expect(err).NotTo(HaveOccurred())
+					_, err = entity.Query(context.Background(), "SELECT Id FROM Account", sfdc.IncludeDeleted())
+					Expect(err).NotTo(HaveOccurred())
+					Expect(paths).To(Equal([]string{"/services/data/v54.0/query", "/services/data/v54.0/queryAll"}))
+				})
+
+				it("sends query and call options on every page", func() {
+					var err error
+					instance, err = sfdc.New(sfdc.WithNoAuthentication(), sfdc.WithURL(server.URL), sfdc.WithQueryBatchSize(500), sfdc.WithCallClient("etl"))
+					Expect(err).NotTo(HaveOccurred())
+					entity = sfdc.NewEntity[testEntity](instance)
+					calls := 0
+					handler = func(w http.ResponseWriter, r *http.Request) {
+						calls++
+						Expect(r.Header.Get("Sforce-Query-Options")).To(Equal("batchSize=200"))
+						Expect(r.Header.Get("Sforce-Call-Options")).To(Equal("client=etl"))
+						if calls == 1 {
+							w.Write([]byte(`{"records":[{"Id":"1"}], "done":false, "nextRecordsUrl":"/next"}`))
+							return
+						}
+						w.Write([]byte(`{"records":[{"Id":"2"}], "done":true}`))
+					}
+					result, err := entity.Query(context.Background(), "SELECT Id FROM Account", sfdc.BatchSize(200))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result).To(HaveLen(2))
+				})
+
+				it("sends no options by default", func() {
+					handler = func(w http.ResponseWriter, r *http.Request) {
+						Expect(r.Header.Values("Sforce-Query-Options")).To(BeEmpty())
+						Expect(r.Header.Values("Sforce-Call-Options")).To(BeEmpty())
+						w.Write([]byte(`{"records":[], "done":true}`))
+					}
+					_, err := entity.Query(context.Background(), "SELECT Id FROM Account")
+					Expect(err).NotTo(HaveOccurred())
+				})
+			})
+
+			when("ListModifiedSince()", func() {
+				it("queries records modified after the given time", func() {
+					handler = func(w http.ResponseWriter, r *http.Request) {
+						Expect(r.URL.Query().Get("q")).To(Equal("SELECT Id FROM testEntity WHERE LastModifiedDate > 2024-01-02T08:04:05.000Z"))
+						w.Write([]byte(`{"records":[{"Id":"test"}], "done":true}`))
+					}
+					since := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("EST", -5*60*60))
+					records, errs := entity.ListModifiedSince(context.Background(), since)
+					var result []testEntity
+					for rec := range records {
+						result = append(result, rec...)
+					}
+					Expect(errs).To(BeEmpty())
+					Expect(result).To(HaveLen(1))
+				})
+			})
+
+			when("Create()", func() {
+				it("posts the record without its Id and returns the new ID", func() {
+					handler = func(w http.ResponseWriter, r *http.Request) {
+						Expect(r.Method).To(Equal(http.MethodPost))
+						Expect(r.URL.Path).To(Equal("/services/data/v54.0/sobjects/testEntity"))
+						body, err := io.ReadAll(r.Body)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(string(body)).To(Equal(`{}`))
+						w.WriteHeader(http.StatusCreated)
+						w.Write([]byte(`{"id":"001000000000001","success":true,"errors":[]}`))
+					}
+					id, err := entity.Create(context.Background(), &testEntity{ID: "ignored"})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(id).To(Equal(sfdc.ID("001000000000001")))
+				})
+
+				it("returns an error when Salesforce rejects the record", func() {
+					handler = func(w http.ResponseWriter, r *http.Request) {
+						w.WriteHeader(http.StatusBadRequest)
+						w.Write([]byte(`[{"message":"Required fields are missing: [Name]","errorCode":"REQUIRED_FIELD_MISSING"}]`))
+					}
+					_, err := entity.Create(context.Background(), &testEntity{})
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("REQUIRED_FIELD_MISSING"))
+					var apiErr *sfdc.APIError
+					Expect(errors.As(err, &apiErr)).To(BeTrue())
+					Expect(apiErr.StatusCode).To(Equal(http.StatusBadRequest))
+					Expect(apiErr.HasErrorCode("REQUIRED_FIELD_MISSING")).To(BeTrue())
+				})
+			})
+
+			when("Get()", func() {
+				it("fetches the record by ID", func() {
+					handler = func(w http.ResponseWriter, r *http.Request) {
+						Expect(r.Method).To(Equal(http.MethodGet))
+						Expect(r.URL.Path).To(Equal("/services/data/v54.0/sobjects/testEntity/001000000000001"))
+						w.Write([]byte(`{"attributes":{"type":"testEntity"},"Id":"001000000000001"}`))
+					}
+					result, err := entity.Get(context.Background(), "001000000000001")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result.ID).To(Equal("001000000000001"))
+				})
+			})
+
+			when("Update()", func() {
+				it("patches the record by ID", func() {
+					handler = func(w http.ResponseWriter, r *http.Request) {
+						Expect(r.Method).To(Equal(http.MethodPatch))
+						Expect(r.URL.Path).To(Equal("/services/data/v54.0/sobjects/testEntity/001000000000001"))
+						w.WriteHeader(http.StatusNoContent)
+					}
+					err := entity.Update(context.Background(), "001000000000001", &testEntity{})
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				it("does not write read-only fields or zero times", func() {
+					type Account struct {
+						ID               string    `json:"Id"`
+						Name             string    `json:"Name"`
+						CloseDate        sfdc.Date `json:"CloseDate__c"`
+						CreatedDate      time.Time `json:"CreatedDate" sfdc:",readonly"`
+						LastModifiedDate time.Time `json:"LastModifiedDate"`
+						Status           string    `json:"Status" sfdc:"toLabel(Status),readonly"`
+					}
+					handler = func(w http.ResponseWriter, r *http.Request) {
+						body, err := io.ReadAll(r.Body)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(string(body)).To(Equal(`{"Name":"Acme"}`))
+						w.WriteHeader(http.StatusNoContent)
+					}
+					accounts := sfdc.NewEntity[Account](instance)
+					Expect(accounts.TaggedFields()).To(Equal("Id,Name,CloseDate__c,CreatedDate,LastModifiedDate,toLabel(Status)"))
+					err := accounts.Update(context.Background(), "001000000000001", &Account{
+						Name:        "Acme",
+						CreatedDate: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+						Status:      "Open",
+					})
+					Expect(err).NotTo(HaveOccurred())
+				})
+			})
+
+			when("Upsert()", func() {
+				it("reports a created record", func() {
+					handler = func(w http.ResponseWriter, r *http.Request) {
+						Expect(r.Method).To(Equal(http.MethodPatch))
+						Expect(r.URL.Path).To(Equal("/services/data/v54.0/sobjects/testEntity/External_Id__c/ERP-1"))
+						w.WriteHeader(http.StatusCreated)
+						w.Write([]byte(`{"id":"001000000000001","success":true,"errors":[],"created":true}`))
+					}
+					result, err := entity.Upsert(context.Background(), "External_Id__c", "ERP-1", &testEntity{})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result.ID).To(Equal(sfdc.ID("001000000000001")))
+					Expect(result.Created).To(BeTrue())
+				})
+
+				it("reports an updated record", func() {
+					handler = func(w http.ResponseWriter, r *http.Request) {
+						w.WriteHeader(http.StatusNoContent)
+					}
+					result, err := entity.Upsert(context.Background(), "External_Id__c", "ERP-1", &testEntity{})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result.Success).To(BeTrue())
+					Expect(result.Created).To(BeFalse())
+				})
+			})
+
+			when("Delete()", func() {
+				it("deletes the record by ID", func() {
+					handler = func(w http.ResponseWriter, r *http.Request) {
+						Expect(r.Method).To(Equal(http.MethodDelete))
+						Expect(r.URL.Path).To(Equal("/services/data/v54.0/sobjects/testEntity/001000000000001"))
+						w.WriteHeader(http.StatusNoContent)
+					}
+					err := entity.Delete(context.Background(), "001000000000001")
+					Expect(err).NotTo(HaveOccurred())
+				})
+			})
+
+			when("Query() decodes parent relationships", func() {
+				it("returns records with nested parents", func() {
+					type user struct {
+						Name string `json:"Name"`
+					}
+					type contact struct {
+						ID    string `json:"Id"`
+						Owner *user  `json:"Owner"`
+					}
+					handler = func(w http.ResponseWriter, r *http.Request) {
+						w.Write([]byte(`{"records":[
+							{"attributes":{"type":"Contact"},"Id":"003000000000001","Owner":{"attributes":{"type":"User"},"Name":"Jane"}},
+							{"attributes":{"type":"Contact"},"Id":"003000000000002","Owner":null}
+						], "done":true}`))
+					}
+					result, err := sfdc.NewEntity[contact](instance).Query(context.Background(), "SELECT Id, Owner.Name FROM Contact")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result).To(HaveLen(2))
+					Expect(result[0].Owner.Name).To(Equal("Jane"))
+					Expect(result[1].Owner).To(BeNil())
+				})
+			})
+
+			when("Query() decodes child relationships", func() {
+				it("unwraps subquery results and fetches the remaining child records", func() {
+					type contact struct {
+						ID string `json:"Id"`
+					}
+					type account struct {
+						ID       string    `json:"Id"`
+						Contacts []contact `json:"Contacts"`
+					}
+					handler = func(w http.ResponseWriter, r *http.Request) {
+						if r.URL.Path == "/services/data/v54.0/query/01g000000000001-2000" {
+							w.Write([]byte(`{"totalSize":3,"done":true,"records":[{"Id":"003000000000003"}]}`))
+							return
+						}
+						Expect(r.URL.Query().Get("q")).To(Equal("SELECT Id,(SELECT Id FROM Contacts) FROM account"))
+						w.Write([]byte(`{"done":true,"records":[
+							{"Id":"001000000000001","Contacts":{"totalSize":3,"done":false,"nextRecordsUrl":"/services/data/v54.0/query/01g000000000001-2000","records":[{"Id":"003000000000001"},{"Id":"003000000000002"}]}},
+							{"Id":"001000000000002","Contacts":null}
+						]}`))
+					}
+					accounts := sfdc.NewEntity[account](instance)
+					result, err := accounts.Query(context.Background(), accounts.Select().String())
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result).To(HaveLen(2))
+					Expect(result[0].Contacts).To(Equal([]contact{{ID: "003000000000001"}, {ID: "003000000000002"}, {ID: "003000000000003"}}))
+					Expect(result[1].Contacts).To(BeEmpty())
+				})
+
+				it("reads child records returned under the relationship name", func() {
+					type contact struct {
+						ID string `json:"Id"`
+					}
+					type account struct {
+						ID       string    `json:"Id"`
+						Contacts []contact `json:"contacts" sfdc:"Contacts"`
+					}
+					handler = func(w http.ResponseWriter, r *http.Request) {
+						Expect(r.URL.Query().Get("q")).To(Equal("SELECT Id,(SELECT Id FROM Contacts) FROM account"))
+						w.Write([]byte(`{"done":true,"records":[
+							{"Id":"001000000000001","Contacts":{"totalSize":1,"done":true,"records":[{"Id":"003000000000001"}]}}
+						]}`))
+					}
+					accounts := sfdc.NewEntity[account](instance)
+					result, err := accounts.Query(context.Background(), accounts.Select().String())
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result).To(Equal([]account{{ID: "001000000000001", Contacts: []contact{{ID: "003000000000001"}}}}))
+				})
 			})
 
 			when("QueryAsync", func() {
@@ -181,6 +466,135 @@ func testEntity(t *testing.T, when spec.G, it spec.S) {
 					Expect(result).NotTo(BeNil())
 					Expect(result).To(HaveLen(2))
 				})
+
+				it("stops when ctx is cancelled", func() {
+					handler = func(w http.ResponseWriter, r *http.Request) {
+						w.Write([]byte(`{"records":[{"Id": "test"}], "done":false, "nextRecordsUrl": "/next"}`))
+					}
+					ctx, cancel := context.WithCancel(context.Background())
+					records, errs := entity.QueryAsync(ctx, "SELECT Id FROM Account")
+					<-records
+					cancel()
+					Eventually(records).Should(BeClosed())
+					Expect(<-errs).To(MatchError(context.Canceled))
+				})
+			})
+
+			when("All()", func() {
+				var calls int
+
+				it.Before(func() {
+					calls = 0
+					handler = func(w http.ResponseWriter, r *http.Request) {
+						calls++
+						if calls == 1 {
+							w.Write([]byte(`{"records":[{"Id":"1"},{"Id":"2"}], "done":false, "nextRecordsUrl":"/next"}`))
+							return
+						}
+						w.Write([]byte(`{"records":[{"Id":"3"}], "done":true}`))
+					}
+				})
+
+				it("yields every record", func() {
+					var ids []string
+					for rec, err := range entity.All(context.Background(), "SELECT Id FROM Account") {
+						Expect(err).NotTo(HaveOccurred())
+						ids = append(ids, rec.ID)
+					}
+					Expect(ids).To(Equal([]string{"1", "2", "3"}))
+					Expect(calls).To(Equal(2))
+				})
+
+				it("stops fetching pages after a break", func() {
+					for rec, err := range entity.All(context.Background(), "SELECT Id FROM Account") {
+						Expect(err).NotTo(HaveOccurred())
+						if rec.ID == "2" {
+							break
+						}
+					}
+					Expect(calls).To(Equal(1))
+				})
+
+				it("yields an error and stops", func() {
+					handler = func(w http.ResponseWriter, r *http.Request) {
+						w.WriteHeader(http.StatusBadRequest)
+						w.Write([]byte(`[{"message":"unexpected token","errorCode":"MALFORMED_QUERY"}]`))
+					}
+					count := 0
+					var err error
+					for _, err = range entity.All(context.Background(), "SELECT") {
+						count++
+					}
+					Expect(count).To(Equal(1))
+					Expect(err).To(MatchError(ContainSubstring("MALFORMED_QUERY")))
+				})
+			})
+
+			when("Pages()", func() {
+				it("yields each page", func() {
+					calls := 0
+					handler = func(w http.ResponseWriter, r *http.Request) {
+						calls++
+						if calls == 1 {
+							w.Write([]byte(`{"records":[{"Id":"1"},{"Id":"2"}], "done":false, "nextRecordsUrl":"/next"}`))
+							return
+						}
+						w.Write([]byte(`{"records":[{"Id":"3"}], "done":true}`))
+					}
+					var sizes []int
+					for page, err := range entity.Pages(context.Background(), "SELECT Id FROM Account") {
+						Expect(err).NotTo(HaveOccurred())
+						sizes = append(sizes, len(page))
+					}
+					Expect(sizes).To(Equal([]int{2, 1}))
+				})
+			})
+
+			when("Resume()", func() {
+				it.Before(func() {
+					handler = func(w http.ResponseWriter, r *http.Request) {
+						switch r.URL.Path {
+						case "/services/data/v54.0/queryAll":
+							w.Write([]byte(`{"records":[{"Id":"1"}], "done":false, "nextRecordsUrl":"/services/data/v54.0/query/01g-2000"}`))
+						case "/services/data/v54.0/query/01g-2000":
+							w.Write([]byte(`{"records":[{"Id":"2"}], "done":false, "nextRecordsUrl":"/services/data/v54.0/query/01g-4000"}`))
+						case "/services/data/v54.0/query/01g-4000":
+							w.Write([]byte(`{"records":[{"Id":"3"}], "done":true}`))
+						default:
+							w.WriteHeader(http.StatusNotFound)
+						}
+					}
+				})
+
+				it("continues a query from a saved cursor", func() {
+					var cursor sfdc.Cursor
+					for page, err := range entity.Responses(context.Background(), "SELECT Id FROM Account") {
+						Expect(err).NotTo(HaveOccurred())
+						cursor = page.Cursor()
+						break
+					}
+					Expect(cursor).To(Equal(sfdc.Cursor("/services/data/v54.0/query/01g-2000")))
+
+					var ids []string
+					var last sfdc.Cursor
+					for page, err := range entity.Resume(context.Background(), cursor) {
+						Expect(err).NotTo(HaveOccurred())
+						for _, rec := range page.Records {
+							ids = append(ids, rec.ID)
+						}
+						last = page.Cursor()
+					}
+					Expect(ids).To(Equal([]string{"2", "3"}))
+					Expect(last).To(BeEmpty())
+				})
+
+				it("yields nothing for an empty cursor", func() {
+					count := 0
+					for range entity.Resume(context.Background(), "") {
+						count++
+					}
+					Expect(count).To(BeZero())
+				})
 			})
 		})
 	})
@@ -0,0 +1,131 @@
+package sfdc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// maxErrorBody is the most of an error response body that is read.
+const maxErrorBody = 1 << 20
+
+// Errors matched by an APIError with the corresponding error code, e.g.
+// errors.Is(err, ErrInvalidSession).
+var (
+	ErrInvalidSession       = errors.New("invalid session")
+	ErrRequestLimitExceeded = errors.New("request limit exceeded")
+	ErrMalformedQuery       = errors.New("malformed query")
+)
+
+// errorCodes maps each sentinel error to the Salesforce error code it
+// matches.
+var errorCodes = map[error]string{
+	ErrInvalidSession:       "INVALID_SESSION_ID",
+	ErrRequestLimitExceeded: "REQUEST_LIMIT_EXCEEDED",
+	ErrMalformedQuery:       "MALFORMED_QUERY",
+}
+
+// APIError is returned when Salesforce responds to a request with an error.
+type APIError struct {
+	// StatusCode is the HTTP status of the response.
+	StatusCode int
+	// URL is the URL of the request, when known.
+	URL string
+	// LimitInfo holds the Sforce-Limit-Info header of the response.
+	LimitInfo string
+	// Errors holds the errors reported in the response body.
+	Errors []ErrorDetail
+	// Body holds the response body when it does not describe any errors,
+	// such as the HTML page of a proxy or maintenance outage.
+	Body string
+}
+
+// ErrorDetail is a single error reported by Salesforce.
+type ErrorDetail struct {
+	Message   string   `json:"message"`
+	ErrorCode string   `json:"errorCode"`
+	Fields    []string `json:"fields,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("unexpected response: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	messages := make([]string, 0, len(e.Errors))
+	for _, detail := range e.Errors {
+		messages = append(messages, fmt.Sprintf("%s (%s)", detail.Message, detail.ErrorCode))
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Is reports whether target is the sentinel error for one of the error
+// codes of e.
+func (e *APIError) Is(target error) bool {
+	code, ok := errorCodes[target]
+	return ok && e.HasErrorCode(code)
+}
+
+// HasErrorCode reports whether any of the errors of e has the given code.
+func (e *APIError) HasErrorCode(code string) bool {
+	return slices.ContainsFunc(e.Errors, func(detail ErrorDetail) bool {
+		return detail.ErrorCode == code
+	})
+}
+
+// errorForResponse reads the body of res and returns the error it
+// describes.
+func errorForResponse(res *http.Response) error {
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxErrorBody))
+	if err != nil {
+		return err
+	}
+	return responseError(res, body)
+}
+
+// responseError returns the error described by body, the body of res.
+func responseError(res *http.Response, body []byte) *APIError {
+	result := parseAPIError(res.StatusCode, body)
+	if res.Request != nil && res.Request.URL != nil {
+		result.URL = res.Request.URL.String()
+	}
+	result.LimitInfo = res.Header.Get("Sforce-Limit-Info")
+	return result
+}
+
+// parseAPIError returns the error described by body. Salesforce usually
+// reports a list of errors, but a few resources report a single error, and
+// authentication failures use the OAuth error form.
+func parseAPIError(status int, body []byte) *APIError {
+	result := &APIError{StatusCode: status}
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &result.Errors); err == nil && len(result.Errors) > 0 {
+			return result
+		}
+	}
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		var single struct {
+			ErrorDetail
+			Error            string `json:"error"`
+			ErrorDescription string `json:"error_description"`
+		}
+		if err := json.Unmarshal(trimmed, &single); err == nil {
+			switch {
+			case single.ErrorCode != "" || single.Message != "":
+				result.Errors = []ErrorDetail{single.ErrorDetail}
+				return result
+			case single.Error != "":
+				result.Errors = []ErrorDetail{{Message: single.ErrorDescription, ErrorCode: single.Error}}
+				return result
+			}
+		}
+	}
+	result.Errors = nil
+	result.Body = string(trimmed)
+	return result
+}
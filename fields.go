@@ -1,10 +1,14 @@
 package sfdc
 
 import (
+	"encoding/json"
+	"fmt"
 	"reflect"
 	"strings"
 )
 
+var unmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+
 // deepFields recurses struct types to fetch types embedded in a struct.
 func deepFields(typ reflect.Type) []reflect.StructField {
 	fields := make([]reflect.StructField, 0)
@@ -26,37 +30,198 @@ func deepFields(typ reflect.Type) []reflect.StructField {
 	return fields
 }
 
-func fieldsForType(t reflect.Type) string {
+// defaultRelationshipDepth is the number of parent relationship levels
+// followed by default, which is the most SOQL allows.
+const defaultRelationshipDepth = 5
+
+// fieldsForType returns the fields selected for t. Nested structs are
+// parent relationships, selected as dotted paths such as Owner.Name, up to
+// depth levels deep. Slices of structs are child relationships, selected
+// with a subquery such as (SELECT Id,Name FROM Contacts). Interfaces
+// registered with RegisterPolymorphic are selected with TYPEOF.
+func fieldsForType(t reflect.Type, depth int) string {
+	return strings.Join(selectFields(t, "", depth, true), ",")
+}
+
+// selectFields returns the fields selected for t, prefixed with prefix.
+// Subqueries are only selected when subqueries is set, as they cannot be
+// nested or selected through a parent relationship.
+func selectFields(t reflect.Type, prefix string, depth int, subqueries bool) []string {
 	result := []string{}
 	fields := deepFields(t)
 	for _, field := range fields {
-		target := field.Name
-		skip := false
-		if jsonTag, ok := field.Tag.Lookup("json"); ok {
-			jsonTag = strings.TrimSpace(jsonTag)
-			if jsonTag == "-" {
-				skip = true
-				continue
-			} else {
-				segments := strings.Split(jsonTag, ",")
-				if len(segments) > 0 && strings.TrimSpace(segments[0]) != "" {
-					target = strings.TrimSpace(segments[0])
-				}
+		target, ok := fieldTarget(field)
+		if !ok {
+			continue
+		}
+		if parent, ok := parentRelationship(field); ok {
+			if depth > 0 {
+				result = append(result, selectFields(parent, prefix+target+".", depth-1, false)...)
 			}
+			continue
 		}
-		if sfdcTag, ok := field.Tag.Lookup("sfdc"); ok {
-			sfdcTag = strings.TrimSpace(sfdcTag)
-			if sfdcTag != "" {
-				skip = false
-				target = sfdcTag
+		if variants, ok := polymorphicVariants(field.Type); ok {
+			if prefix == "" {
+				result = append(result, typeOf(target, variants))
 			}
-			if sfdcTag == "-" {
-				skip = true
+			continue
+		}
+		if isChildRelationship(field) && !strings.Contains(target, "(") {
+			if subqueries {
+				children := selectFields(sliceElem(field.Type), "", depth, false)
+				result = append(result, fmt.Sprintf("(SELECT %s FROM %s)", strings.Join(children, ","), childRelationshipName(field)))
 			}
+			continue
+		}
+		result = append(result, prefix+target)
+	}
+	return result
+}
+
+// fieldTarget returns what is selected for field: its sfdc tag when set,
+// otherwise its JSON key. It returns false if the field is skipped.
+func fieldTarget(field reflect.StructField) (string, bool) {
+	target, ok := jsonName(field)
+	if !ok {
+		return "", false
+	}
+	sfdcTag, _ := sfdcTagOf(field)
+	if sfdcTag == "-" {
+		return "", false
+	}
+	if sfdcTag != "" {
+		target = sfdcTag
+	}
+	return target, true
+}
+
+// sfdcTagOf returns the sfdc tag of field without its options, and whether
+// the field is tagged readonly, as in sfdc:",readonly" or
+// sfdc:"toLabel(Status),readonly". Read-only fields are selected but never
+// written.
+func sfdcTagOf(field reflect.StructField) (string, bool) {
+	sfdcTag := strings.TrimSpace(field.Tag.Get("sfdc"))
+	if i := strings.LastIndex(sfdcTag, ","); i >= 0 && strings.TrimSpace(sfdcTag[i+1:]) == "readonly" {
+		return strings.TrimSpace(sfdcTag[:i]), true
+	}
+	return sfdcTag, false
+}
+
+// jsonName returns the key encoding/json uses for field, or false if the
+// field is not encoded.
+func jsonName(field reflect.StructField) (string, bool) {
+	name := field.Name
+	if jsonTag, ok := field.Tag.Lookup("json"); ok {
+		jsonTag = strings.TrimSpace(jsonTag)
+		if jsonTag == "-" {
+			return "", false
+		}
+		segments := strings.Split(jsonTag, ",")
+		if strings.TrimSpace(segments[0]) != "" {
+			name = strings.TrimSpace(segments[0])
+		}
+	}
+	return name, true
+}
+
+// readOnlyFieldsForType returns the JSON keys of fields that are read from
+// Salesforce but must not be written back: fields tagged sfdc:"-" or
+// sfdc:",readonly", fields whose sfdc tag is an expression or subquery, and
+// relationships.
+func readOnlyFieldsForType(t reflect.Type) map[string]bool {
+	result := map[string]bool{}
+	for _, field := range deepFields(t) {
+		name, ok := jsonName(field)
+		if !ok {
+			continue
+		}
+		sfdcTag, readOnly := sfdcTagOf(field)
+		_, isParent := parentRelationship(field)
+		_, isPolymorphic := polymorphicVariants(field.Type)
+		if readOnly || sfdcTag == "-" || strings.Contains(sfdcTag, "(") || isChildRelationship(field) || isParent || isPolymorphic {
+			result[name] = true
+		}
+	}
+	return result
+}
+
+// parentRelationship returns the type of the parent record held by field, a
+// nested struct such as Owner User or Owner *User. Structs that decode
+// themselves, such as time.Time, and structs with an sfdc tag, which is
+// selected as is, are not relationships.
+func parentRelationship(field reflect.StructField) (reflect.Type, bool) {
+	if sfdcTag, _ := sfdcTagOf(field); sfdcTag != "" {
+		return nil, false
+	}
+	typ := field.Type
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct || typ.Implements(unmarshalerType) || reflect.PointerTo(typ).Implements(unmarshalerType) {
+		return nil, false
+	}
+	return typ, true
+}
+
+// isChildRelationship reports whether field holds the records of a child
+// relationship, such as Contacts []Contact on an Account.
+func isChildRelationship(field reflect.StructField) bool {
+	if field.Type.Kind() != reflect.Slice {
+		return false
+	}
+	elem := sliceElem(field.Type)
+	return elem.Kind() == reflect.Struct && !elem.Implements(unmarshalerType) && !reflect.PointerTo(elem).Implements(unmarshalerType)
+}
+
+// childRelationshipName returns the name of the child relationship held by
+// field: its sfdc tag when that is a plain name, otherwise its JSON key.
+func childRelationshipName(field reflect.StructField) string {
+	sfdcTag, _ := sfdcTagOf(field)
+	if sfdcTag != "" && sfdcTag != "-" && !strings.ContainsAny(sfdcTag, "(), ") {
+		return sfdcTag
+	}
+	name, _ := jsonName(field)
+	return name
+}
+
+// childRelationship is a field of a record that holds the records of a
+// child relationship.
+type childRelationship struct {
+	// key is the field's JSON key.
+	key string
+	// name is the relationship name, which Salesforce returns the records
+	// under.
+	name string
+	// field is the field's Go name.
+	field string
+	// polymorphic holds the polymorphic relationships of the child records.
+	polymorphic []polymorphicField
+}
+
+// childRelationshipsForType returns the child relationships of t.
+func childRelationshipsForType(t reflect.Type) []childRelationship {
+	result := []childRelationship{}
+	for _, field := range deepFields(t) {
+		if !isChildRelationship(field) {
+			continue
 		}
-		if !skip {
-			result = append(result, target)
+		if key, ok := jsonName(field); ok {
+			result = append(result, childRelationship{
+				key:         key,
+				name:        childRelationshipName(field),
+				field:       field.Name,
+				polymorphic: polymorphicFieldsForType(sliceElem(field.Type)),
+			})
 		}
 	}
-	return strings.Join(result, ",")
+	return result
+}
+
+// sliceElem returns the element type of a slice, dereferencing pointers.
+func sliceElem(t reflect.Type) reflect.Type {
+	elem := t.Elem()
+	if elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	return elem
 }
@@ -2,6 +2,7 @@ package sfdc_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/joefitzgerald/sfdc"
 	. "github.com/onsi/gomega"
@@ -63,4 +64,70 @@ func testFields(t *testing.T, when spec.G, it spec.S) {
 		e := sfdc.NewEntity[t](&sfdc.Instance{})
 		Expect(e.TaggedFields()).To(Equal("id,name,(SELECT Id,AttachmentId FROM Attachment)"))
 	})
+
+	when("a field is a nested struct", func() {
+		type user struct {
+			Name  string `json:"Name"`
+			Email string `json:"Email"`
+		}
+		type account struct {
+			Name  string `json:"Name"`
+			Owner *user  `json:"Owner"`
+		}
+		type t struct {
+			ID               string    `json:"Id"`
+			Owner            user      `json:"Owner"`
+			Account          account   `json:"Account"`
+			LastModifiedDate time.Time `json:"LastModifiedDate"`
+		}
+
+		it("selects relationship fields as dotted paths", func() {
+			e := sfdc.NewEntity[t](&sfdc.Instance{})
+			Expect(e.TaggedFields()).To(Equal("Id,Owner.Name,Owner.Email,Account.Name,Account.Owner.Name,Account.Owner.Email,LastModifiedDate"))
+		})
+
+		it("limits the depth of relationships", func() {
+			e := sfdc.NewEntity[t](&sfdc.Instance{})
+			e.SetRelationshipDepth(1)
+			Expect(e.TaggedFields()).To(Equal("Id,Owner.Name,Owner.Email,Account.Name,LastModifiedDate"))
+			e.SetRelationshipDepth(0)
+			Expect(e.TaggedFields()).To(Equal("Id,LastModifiedDate"))
+		})
+
+		it("selects structs with an sfdc tag as is", func() {
+			type address struct {
+				City string `json:"city"`
+			}
+			type t struct {
+				BillingAddress address `json:"BillingAddress" sfdc:"BillingAddress"`
+			}
+			e := sfdc.NewEntity[t](&sfdc.Instance{})
+			Expect(e.TaggedFields()).To(Equal("BillingAddress"))
+		})
+	})
+
+	when("a field is a slice of structs", func() {
+		type contact struct {
+			ID   string `json:"Id"`
+			Name string `json:"Name"`
+		}
+
+		it("selects the child relationship with a subquery", func() {
+			type t struct {
+				ID       string    `json:"Id"`
+				Contacts []contact `json:"contacts" sfdc:"Contacts"`
+			}
+			e := sfdc.NewEntity[t](&sfdc.Instance{})
+			Expect(e.TaggedFields()).To(Equal("Id,(SELECT Id,Name FROM Contacts)"))
+		})
+
+		it("uses the JSON key when there is no sfdc tag", func() {
+			type t struct {
+				ID       string     `json:"Id"`
+				Contacts []*contact `json:"Contacts"`
+			}
+			e := sfdc.NewEntity[t](&sfdc.Instance{})
+			Expect(e.TaggedFields()).To(Equal("Id,(SELECT Id,Name FROM Contacts)"))
+		})
+	})
 }
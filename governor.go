@@ -0,0 +1,140 @@
+package sfdc
+
+import (
+	"context"
+	"time"
+)
+
+// Priority is the importance of a request to a Governor.
+type Priority int
+
+const (
+	// PriorityNormal requests are slowed as the daily API allocation runs
+	// low. It is the priority of requests without one.
+	PriorityNormal Priority = iota
+	// PriorityBackground requests are slowed like normal requests, and
+	// paused once the allocation reaches the reserve.
+	PriorityBackground
+	// PriorityCritical requests are never slowed or paused.
+	PriorityCritical
+)
+
+type priorityKey struct{}
+
+// WithPriority returns a copy of ctx that gives requests made with it the
+// priority p.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// priorityFrom returns the priority of requests made with ctx.
+func priorityFrom(ctx context.Context) Priority {
+	p, _ := ctx.Value(priorityKey{}).(Priority)
+	return p
+}
+
+// Governor slows requests as the org's daily API allocation, reported in
+// Sforce-Limit-Info headers, runs low, keeping a reserve for other clients
+// of the org such as interactive users. Zero fields take their default
+// values.
+type Governor struct {
+	// Reserve is the fraction of the daily allocation kept back, e.g. 0.2
+	// for 20%. Background requests are paused while no more than the
+	// reserve remains. The default is 0.2.
+	Reserve float64
+	// MaxDelay is the interval between normal and background requests, across
+	// every Entity using the instance, once only the reserve remains. Less of
+	// the allocation used means a shorter interval, in proportion to what
+	// remains above the reserve. The default is one second.
+	MaxDelay time.Duration
+	// PollInterval is how often a paused request checks whether it may
+	// proceed. When no other request has reported the usage for that long,
+	// a paused request is sent to refresh it. The default is one minute.
+	PollInterval time.Duration
+}
+
+type withGovernor struct {
+	governor Governor
+}
+
+func (w *withGovernor) applyToInstance(i *Instance) {
+	governor := w.governor
+	if governor.Reserve <= 0 {
+		governor.Reserve = 0.2
+	}
+	if governor.MaxDelay <= 0 {
+		governor.MaxDelay = time.Second
+	}
+	if governor.PollInterval <= 0 {
+		governor.PollInterval = time.Minute
+	}
+	i.governor = &governor
+	i.throttle = newRateLimiter(0, 1)
+}
+
+// WithGovernor slows requests as the daily API allocation runs low, as
+// described by governor, limiting the rate of requests sent by the instance
+// rather than delaying each one, so that concurrent requests cannot use up
+// the reserve. Use WithPriority to mark requests that must not be
+// slowed, or that can be paused.
+func WithGovernor(governor Governor) InstanceOption {
+	return &withGovernor{governor: governor}
+}
+
+// govern waits until the governor allows a request made with ctx to be
+// sent.
+func (i *Instance) govern(ctx context.Context) error {
+	g := i.governor
+	if g == nil {
+		return nil
+	}
+	priority := priorityFrom(ctx)
+	if priority == PriorityCritical {
+		return nil
+	}
+	for {
+		usage := i.APIUsage()
+		if usage.Max <= 0 {
+			return nil
+		}
+		remaining := float64(usage.Remaining()) / float64(usage.Max)
+		if remaining > g.Reserve {
+			// Scale the delay by how much of the allocation above the
+			// reserve has been used.
+			used := 1 - (remaining-g.Reserve)/(1-g.Reserve)
+			return i.slow(ctx, time.Duration(used*float64(g.MaxDelay)))
+		}
+		if priority != PriorityBackground || time.Since(usage.Updated) >= g.PollInterval {
+			return i.slow(ctx, g.MaxDelay)
+		}
+		if err := sleep(ctx, g.PollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+// slow waits for the instance's turn to send a request, with requests
+// spaced interval apart.
+func (i *Instance) slow(ctx context.Context, interval time.Duration) error {
+	rate := 0.0
+	if interval > 0 {
+		rate = float64(time.Second) / float64(interval)
+	}
+	i.throttle.setRate(rate)
+	return i.throttle.wait(ctx)
+}
+
+// sleep waits for d to pass or ctx to be done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,127 @@
+package sfdc_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/joefitzgerald/sfdc"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+)
+
+func testGovernor(t *testing.T, when spec.G, it spec.S) {
+	type Account struct {
+		ID string `json:"Id"`
+	}
+
+	var (
+		server   *httptest.Server
+		usage    string
+		accounts *sfdc.Entity[Account]
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Sforce-Limit-Info", usage)
+			w.Write([]byte(`{"Id":"001"}`))
+		}))
+	})
+
+	it.After(func() {
+		server.Close()
+	})
+
+	newAccounts := func(governor sfdc.Governor) {
+		instance, err := sfdc.New(sfdc.WithNoAuthentication(), sfdc.WithURL(server.URL), sfdc.WithGovernor(governor))
+		Expect(err).NotTo(HaveOccurred())
+		accounts = sfdc.NewEntity[Account](instance)
+	}
+
+	timeGet := func(ctx context.Context) (time.Duration, error) {
+		start := time.Now()
+		_, err := accounts.Get(ctx, "001")
+		return time.Since(start), err
+	}
+
+	it("does not slow requests while usage is low or unknown", func() {
+		newAccounts(sfdc.Governor{MaxDelay: time.Second})
+		usage = "api-usage=0/100"
+		elapsed, err := timeGet(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		elapsed, err = timeGet(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(elapsed).To(BeNumerically("<", 100*time.Millisecond))
+	})
+
+	it("slows requests in proportion to the allocation used", func() {
+		newAccounts(sfdc.Governor{Reserve: 0.2, MaxDelay: 200 * time.Millisecond})
+		usage = "api-usage=40/100"
+		_, err := timeGet(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		// Half of the allocation above the reserve is used.
+		elapsed, err := timeGet(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(elapsed).To(BeNumerically(">=", 100*time.Millisecond))
+		Expect(elapsed).To(BeNumerically("<", 200*time.Millisecond))
+	})
+
+	when("only the reserve remains", func() {
+		it.Before(func() {
+			newAccounts(sfdc.Governor{Reserve: 0.2, MaxDelay: 50 * time.Millisecond, PollInterval: time.Hour})
+			usage = "api-usage=85/100"
+			_, err := accounts.Get(sfdc.WithPriority(context.Background(), sfdc.PriorityCritical), "001")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it("sends critical requests without delay", func() {
+			elapsed, err := timeGet(sfdc.WithPriority(context.Background(), sfdc.PriorityCritical))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(elapsed).To(BeNumerically("<", 50*time.Millisecond))
+		})
+
+		it("slows normal requests by the maximum delay", func() {
+			elapsed, err := timeGet(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(elapsed).To(BeNumerically(">=", 50*time.Millisecond))
+		})
+
+		it("limits the rate of concurrent normal requests", func() {
+			start := time.Now()
+			var wg sync.WaitGroup
+			for range 5 {
+				wg.Go(func() {
+					_, err := accounts.Get(context.Background(), "001")
+					Expect(err).NotTo(HaveOccurred())
+				})
+			}
+			wg.Wait()
+			Expect(time.Since(start)).To(BeNumerically(">=", 5*50*time.Millisecond))
+		})
+
+		it("pauses background requests", func() {
+			ctx, cancel := context.WithTimeout(sfdc.WithPriority(context.Background(), sfdc.PriorityBackground), 100*time.Millisecond)
+			defer cancel()
+			_, err := timeGet(ctx)
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+		})
+	})
+
+	it("sends a paused background request once the usage is stale", func() {
+		newAccounts(sfdc.Governor{Reserve: 0.2, MaxDelay: time.Millisecond, PollInterval: 30 * time.Millisecond})
+		usage = "api-usage=90/100"
+		_, err := accounts.Get(context.Background(), "001")
+		Expect(err).NotTo(HaveOccurred())
+		usage = "api-usage=10/100"
+		elapsed, err := timeGet(sfdc.WithPriority(context.Background(), sfdc.PriorityBackground))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(elapsed).To(BeNumerically(">=", 30*time.Millisecond))
+		elapsed, err = timeGet(sfdc.WithPriority(context.Background(), sfdc.PriorityBackground))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(elapsed).To(BeNumerically("<", 30*time.Millisecond))
+	})
+}
@@ -0,0 +1,100 @@
+package sfdc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// graphNodeLimit is the maximum number of nodes in a single graph.
+const graphNodeLimit = 500
+
+// CompositeGraph batches independent graphs of subrequests into a single call
+// to the composite graph resource. Each graph succeeds or is rolled back as a
+// whole, independently of the other graphs.
+type CompositeGraph struct {
+	instance *Instance
+	graphs   []*Graph
+}
+
+// Graph is a set of dependent subrequests, or nodes, that are committed
+// together. Nodes are added using the Entity methods ending in In, for
+// example CreateIn, and can refer to earlier nodes in the same graph using
+// Ref.
+type Graph struct {
+	subrequestList
+	ID         string
+	Successful bool
+}
+
+type graphRequest struct {
+	Graphs []graphRequestGraph `json:"graphs"`
+}
+
+type graphRequestGraph struct {
+	GraphID          string                `json:"graphId"`
+	CompositeRequest []compositeSubrequest `json:"compositeRequest"`
+}
+
+type graphResponse struct {
+	Graphs []struct {
+		GraphID       string            `json:"graphId"`
+		GraphResponse compositeResponse `json:"graphResponse"`
+		IsSuccessful  bool              `json:"isSuccessful"`
+	} `json:"graphs"`
+}
+
+// NewCompositeGraph creates an empty composite graph request.
+func (i *Instance) NewCompositeGraph() *CompositeGraph {
+	return &CompositeGraph{instance: i}
+}
+
+// Graph adds a new, empty graph with the given ID.
+func (c *CompositeGraph) Graph(id string) *Graph {
+	graph := &Graph{ID: id}
+	c.graphs = append(c.graphs, graph)
+	return graph
+}
+
+// Execute sends the graphs and populates each Graph and its Subrequests. The
+// returned error joins the errors of any graphs that were rolled back.
+func (c *CompositeGraph) Execute(ctx context.Context) error {
+	body := graphRequest{Graphs: make([]graphRequestGraph, 0, len(c.graphs))}
+	graphs := map[string]*Graph{}
+	for _, graph := range c.graphs {
+		if graph.err != nil {
+			return fmt.Errorf("graph %s: %w", graph.ID, graph.err)
+		}
+		if len(graph.requests) > graphNodeLimit {
+			return fmt.Errorf("graph %s: graphs are limited to %d nodes, got %d", graph.ID, graphNodeLimit, len(graph.requests))
+		}
+		body.Graphs = append(body.Graphs, graphRequestGraph{
+			GraphID:          graph.ID,
+			CompositeRequest: graph.requests,
+		})
+		graphs[graph.ID] = graph
+	}
+	uri, err := c.instance.CompositeURL("graph")
+	if err != nil {
+		return err
+	}
+	var r graphResponse
+	if _, err := c.instance.send(ctx, http.MethodPost, uri.String(), body, &r); err != nil {
+		return err
+	}
+	var errs []error
+	for _, response := range r.Graphs {
+		graph, ok := graphs[response.GraphID]
+		if !ok {
+			continue
+		}
+		graph.Successful = response.IsSuccessful
+		if err := graph.resolve(response.GraphResponse.CompositeResponse); err != nil {
+			errs = append(errs, fmt.Errorf("graph %s: %w", graph.ID, err))
+		} else if !graph.Successful {
+			errs = append(errs, fmt.Errorf("graph %s was rolled back", graph.ID))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,90 @@
+package sfdc_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/joefitzgerald/sfdc"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+)
+
+func testGraph(t *testing.T, when spec.G, it spec.S) {
+	type Account struct {
+		ID   string `json:"Id,omitempty"`
+		Name string `json:"Name,omitempty"`
+	}
+
+	var (
+		server   *httptest.Server
+		handler  func(w http.ResponseWriter, r *http.Request)
+		instance *sfdc.Instance
+		accounts *sfdc.Entity[Account]
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler(w, r)
+		}))
+		var err error
+		instance, err = sfdc.New(sfdc.WithNoAuthentication(), sfdc.WithURL(server.URL))
+		Expect(err).NotTo(HaveOccurred())
+		accounts = sfdc.NewEntity[Account](instance)
+	})
+
+	it.After(func() {
+		server.Close()
+	})
+
+	it("reports the outcome of each graph", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.Method).To(Equal(http.MethodPost))
+			Expect(r.URL.Path).To(Equal("/services/data/v54.0/composite/graph"))
+			var body struct {
+				Graphs []struct {
+					GraphID          string           `json:"graphId"`
+					CompositeRequest []map[string]any `json:"compositeRequest"`
+				} `json:"graphs"`
+			}
+			Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+			Expect(body.Graphs).To(HaveLen(2))
+			Expect(body.Graphs[0].GraphID).To(Equal("g1"))
+			Expect(body.Graphs[0].CompositeRequest[0]["referenceId"]).To(Equal("NewAccount"))
+			w.Write([]byte(`{"graphs":[
+				{"graphId":"g1","isSuccessful":true,"graphResponse":{"compositeResponse":[
+					{"body":{"id":"001000000000001","success":true,"errors":[]},"httpStatusCode":201,"referenceId":"NewAccount"}
+				]}},
+				{"graphId":"g2","isSuccessful":false,"graphResponse":{"compositeResponse":[
+					{"body":[{"message":"Required fields are missing: [Name]","errorCode":"REQUIRED_FIELD_MISSING"}],"httpStatusCode":400,"referenceId":"BadAccount"}
+				]}}
+			]}`))
+		}
+
+		request := instance.NewCompositeGraph()
+		g1 := request.Graph("g1")
+		created := accounts.CreateIn(g1, "NewAccount", &Account{Name: "Acme"})
+		g2 := request.Graph("g2")
+		failed := accounts.CreateIn(g2, "BadAccount", &Account{})
+
+		err := request.Execute(context.Background())
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("graph g2"))
+		Expect(g1.Successful).To(BeTrue())
+		Expect(created.Result.ID).To(Equal(sfdc.ID("001000000000001")))
+		Expect(g2.Successful).To(BeFalse())
+		Expect(failed.Err).To(HaveOccurred())
+	})
+
+	it("rejects graphs with more than 500 nodes", func() {
+		request := instance.NewCompositeGraph()
+		graph := request.Graph("g1")
+		for range 501 {
+			accounts.CreateIn(graph, "NewAccount", &Account{Name: "Acme"})
+		}
+		Expect(request.Execute(context.Background())).NotTo(Succeed())
+	})
+}
@@ -4,12 +4,33 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"sync"
 )
 
 type Instance struct {
-	url        string
-	client     *http.Client
-	apiVersion string
+	url            string
+	client         *http.Client
+	apiVersion     string
+	queryBatchSize int
+	callClient     string
+	// session is the token source of an instance authenticated WithToken.
+	session     *sessionTokenSource
+	retryPolicy *RetryPolicy
+	rateLimiter *rateLimiter
+	governor    *Governor
+	// throttle spaces out the requests the governor slows, across the
+	// instance.
+	throttle *rateLimiter
+	// requestSlots holds a value for each request in flight when the number
+	// of concurrent requests is limited.
+	requestSlots chan struct{}
+
+	usageMu         sync.Mutex
+	usage           APIUsage
+	usageThresholds []float64
+	// usageCrossed holds whether each of usageThresholds has been crossed.
+	usageCrossed     []bool
+	onUsageThreshold func(usage APIUsage, threshold float64)
 }
 
 func New(auth AuthOption, options ...InstanceOption) (*Instance, error) {
@@ -41,3 +62,29 @@ func (i *Instance) QueryURL() url.URL {
 func (i *Instance) QueryAllURL() (*url.URL, error) {
 	return url.Parse(fmt.Sprintf("%s/services/data/%s/queryAll", i.url, i.apiVersion))
 }
+
+// SObjectURL returns the URL for the named sObject. Any segments are path
+// escaped and appended, e.g. SObjectURL("Account", id).
+func (i *Instance) SObjectURL(name string, segments ...string) (*url.URL, error) {
+	return i.dataURL(append([]string{"sobjects", name}, segments...)...)
+}
+
+// CompositeURL returns the URL for the composite resources. Any segments are
+// path escaped and appended, e.g. CompositeURL("sobjects").
+func (i *Instance) CompositeURL(segments ...string) (*url.URL, error) {
+	return i.dataURL(append([]string{"composite"}, segments...)...)
+}
+
+// JobsURL returns the URL for the Bulk API 2.0 job resources. Any segments
+// are path escaped and appended, e.g. JobsURL("ingest", id).
+func (i *Instance) JobsURL(segments ...string) (*url.URL, error) {
+	return i.dataURL(append([]string{"jobs"}, segments...)...)
+}
+
+func (i *Instance) dataURL(segments ...string) (*url.URL, error) {
+	uri := fmt.Sprintf("%s/services/data/%s", i.url, i.apiVersion)
+	for _, segment := range segments {
+		uri = fmt.Sprintf("%s/%s", uri, url.PathEscape(segment))
+	}
+	return url.Parse(uri)
+}
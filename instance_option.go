@@ -41,3 +41,24 @@ func (w *withHTTPClient) applyToInstance(i *Instance) {
 func WithHTTPClient(client *http.Client) InstanceOption {
 	return &withHTTPClient{client: client}
 }
+
+func (w *withBatchSize) applyToInstance(i *Instance) {
+	i.queryBatchSize = w.batchSize
+}
+
+// WithQueryBatchSize sets the number of records requested per page of query
+// results, sent in the Sforce-Query-Options header. Salesforce accepts 200 to
+// 2000, and defaults to 2000.
+func WithQueryBatchSize(n int) InstanceOption {
+	return &withBatchSize{batchSize: n}
+}
+
+func (w *withCallClient) applyToInstance(i *Instance) {
+	i.callClient = w.client
+}
+
+// WithCallClient sets the client name sent in the Sforce-Call-Options header
+// of queries, which Salesforce records in event logs.
+func WithCallClient(client string) InstanceOption {
+	return &withCallClient{client: client}
+}
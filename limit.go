@@ -0,0 +1,154 @@
+package sfdc
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type withRateLimit struct {
+	rps   float64
+	burst int
+}
+
+func (w *withRateLimit) applyToInstance(i *Instance) {
+	i.rateLimiter = newRateLimiter(w.rps, w.burst)
+}
+
+// WithRateLimit limits the requests the instance sends to rps per second on
+// average, allowing bursts of up to burst requests. The limit is shared by
+// every Entity using the instance, and applies to each attempt of a retried
+// request.
+func WithRateLimit(rps float64, burst int) InstanceOption {
+	return &withRateLimit{rps: rps, burst: burst}
+}
+
+type withMaxConcurrentRequests struct {
+	n int
+}
+
+func (w *withMaxConcurrentRequests) applyToInstance(i *Instance) {
+	if w.n > 0 {
+		i.requestSlots = make(chan struct{}, w.n)
+	}
+}
+
+// WithMaxConcurrentRequests limits the requests the instance has in flight
+// at once to n, shared by every Entity using the instance. A request is in
+// flight until its response body has been read and closed.
+func WithMaxConcurrentRequests(n int) InstanceOption {
+	return &withMaxConcurrentRequests{n: n}
+}
+
+// rateLimiter is a token bucket holding up to burst tokens, refilled at rate
+// tokens per second.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rps float64, burst int) *rateLimiter {
+	burst = max(burst, 1)
+	return &rateLimiter{rate: rps, burst: float64(burst), tokens: float64(burst)}
+}
+
+// wait takes a token from the bucket, waiting until one is available or ctx
+// is done. Tokens are taken in the order wait is called.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	if l.rate <= 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	if !l.last.IsZero() {
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	// Reserve a token, owing it when the bucket is empty.
+	l.tokens--
+	delay := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// setRate changes the rate the bucket is refilled at. A rate of zero or
+// less removes the limit. When a limit is added, the bucket starts empty so
+// that the next request waits for a token.
+func (l *rateLimiter) setRate(rps float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rate == rps {
+		return
+	}
+	now := time.Now()
+	if l.rate > 0 {
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	} else {
+		l.tokens = 0
+	}
+	l.last = now
+	l.rate = rps
+}
+
+// acquire waits until the instance's limits allow another request to be
+// sent, returning a function that ends the request.
+func (i *Instance) acquire(ctx context.Context) (func(), error) {
+	if err := i.govern(ctx); err != nil {
+		return nil, err
+	}
+	if i.rateLimiter != nil {
+		if err := i.rateLimiter.wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if i.requestSlots == nil {
+		return func() {}, nil
+	}
+	select {
+	case i.requestSlots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return sync.OnceFunc(func() { <-i.requestSlots }), nil
+}
+
+// releaseOnClose calls release once the body of res is closed, or now when
+// there is no response.
+func releaseOnClose(res *http.Response, release func()) *http.Response {
+	if res == nil {
+		release()
+		return nil
+	}
+	res.Body = &releasingBody{ReadCloser: res.Body, release: release}
+	return res
+}
+
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	defer b.release()
+	return b.ReadCloser.Close()
+}
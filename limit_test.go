@@ -0,0 +1,88 @@
+package sfdc_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/joefitzgerald/sfdc"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+)
+
+func testLimit(t *testing.T, when spec.G, it spec.S) {
+	type Account struct {
+		ID string `json:"Id"`
+	}
+
+	var (
+		server   *httptest.Server
+		inFlight atomic.Int32
+		peak     atomic.Int32
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		inFlight.Store(0)
+		peak.Store(0)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			w.Write([]byte(`{"Id":"001"}`))
+		}))
+	})
+
+	it.After(func() {
+		server.Close()
+	})
+
+	getAll := func(accounts *sfdc.Entity[Account], n int) {
+		var wg sync.WaitGroup
+		for range n {
+			wg.Go(func() {
+				_, err := accounts.Get(context.Background(), "001")
+				Expect(err).NotTo(HaveOccurred())
+			})
+		}
+		wg.Wait()
+	}
+
+	it("limits the requests in flight", func() {
+		instance, err := sfdc.New(sfdc.WithNoAuthentication(), sfdc.WithURL(server.URL), sfdc.WithMaxConcurrentRequests(2))
+		Expect(err).NotTo(HaveOccurred())
+		getAll(sfdc.NewEntity[Account](instance), 8)
+		Expect(peak.Load()).To(BeEquivalentTo(2))
+	})
+
+	it("limits the rate of requests after a burst", func() {
+		instance, err := sfdc.New(sfdc.WithNoAuthentication(), sfdc.WithURL(server.URL), sfdc.WithRateLimit(50, 2))
+		Expect(err).NotTo(HaveOccurred())
+		start := time.Now()
+		getAll(sfdc.NewEntity[Account](instance), 6)
+		// Two requests are sent at once and the other four 20ms apart.
+		Expect(time.Since(start)).To(BeNumerically(">=", 80*time.Millisecond))
+	})
+
+	it("stops waiting when ctx is done", func() {
+		instance, err := sfdc.New(sfdc.WithNoAuthentication(), sfdc.WithURL(server.URL), sfdc.WithRateLimit(0.1, 1))
+		Expect(err).NotTo(HaveOccurred())
+		accounts := sfdc.NewEntity[Account](instance)
+		_, err = accounts.Get(context.Background(), "001")
+		Expect(err).NotTo(HaveOccurred())
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, err = accounts.Get(ctx, "001")
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+}
@@ -0,0 +1,206 @@
+package sfdc
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// polymorphic holds the variants registered for each polymorphic interface.
+var polymorphic = struct {
+	sync.RWMutex
+	variants map[reflect.Type][]reflect.Type
+}{variants: map[reflect.Type][]reflect.Type{}}
+
+// RegisterPolymorphic registers the sObject types that a polymorphic
+// relationship, such as Task.What, can refer to. I is an interface
+// implemented by each variant, and a field of type I is selected with
+// TYPEOF and decoded into the variant named by the record's type:
+//
+//	type What interface{ isWhat() }
+//	func (Account) isWhat()     {}
+//	func (Opportunity) isWhat() {}
+//
+//	sfdc.RegisterPolymorphic[What](Account{}, Opportunity{})
+//
+//	type Task struct {
+//		ID   string `json:"Id"`
+//		What What   `json:"What"`
+//	}
+//
+// The sObject name of a variant is its type name. Variants must be registered
+// before NewEntity is called for a type that uses I.
+func RegisterPolymorphic[I any](variants ...I) {
+	iface := reflect.TypeFor[I]()
+	if iface.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("sfdc: RegisterPolymorphic requires an interface type, got %s", iface))
+	}
+	polymorphic.Lock()
+	defer polymorphic.Unlock()
+	for _, variant := range variants {
+		typ := reflect.TypeOf(variant)
+		if !slices.Contains(polymorphic.variants[iface], typ) {
+			polymorphic.variants[iface] = append(polymorphic.variants[iface], typ)
+		}
+	}
+}
+
+// polymorphicVariants returns the variants registered for t.
+func polymorphicVariants(t reflect.Type) ([]reflect.Type, bool) {
+	if t.Kind() != reflect.Interface {
+		return nil, false
+	}
+	polymorphic.RLock()
+	defer polymorphic.RUnlock()
+	variants, ok := polymorphic.variants[t]
+	return variants, ok
+}
+
+// variantName returns the sObject name of a variant.
+func variantName(t reflect.Type) string {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+// typeOf returns the TYPEOF clause that selects the polymorphic relationship
+// target from each of variants.
+func typeOf(target string, variants []reflect.Type) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "TYPEOF %s", target)
+	for _, variant := range variants {
+		elem := variant
+		if elem.Kind() == reflect.Pointer {
+			elem = elem.Elem()
+		}
+		fmt.Fprintf(&b, " WHEN %s THEN %s", variantName(variant), strings.Join(selectFields(elem, "", 0, false), ","))
+	}
+	b.WriteString(" END")
+	return b.String()
+}
+
+// polymorphicField is a field of a record that holds a polymorphic
+// relationship.
+type polymorphicField struct {
+	// key is the field's JSON key.
+	key string
+	// name is the field's Go name.
+	name     string
+	variants []reflect.Type
+}
+
+// polymorphicFieldsForType returns the polymorphic relationships of t.
+func polymorphicFieldsForType(t reflect.Type) []polymorphicField {
+	result := []polymorphicField{}
+	for _, field := range deepFields(t) {
+		variants, ok := polymorphicVariants(field.Type)
+		if !ok {
+			continue
+		}
+		if key, ok := jsonName(field); ok {
+			result = append(result, polymorphicField{key: key, name: field.Name, variants: variants})
+		}
+	}
+	return result
+}
+
+// decodeRecord decodes raw into v, decoding each polymorphic field into the
+// variant named by the type in its attributes, including those of the
+// records of children.
+func decodeRecord(raw json.RawMessage, v reflect.Value, fields []polymorphicField, children []childRelationship) error {
+	polymorphicChildren := slices.ContainsFunc(children, func(child childRelationship) bool {
+		return len(child.polymorphic) > 0
+	})
+	if len(fields) == 0 && !polymorphicChildren {
+		return json.Unmarshal(raw, v.Addr().Interface())
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return err
+	}
+	targets := map[string]json.RawMessage{}
+	for _, field := range fields {
+		if value, ok := values[field.key]; ok {
+			targets[field.key] = value
+			delete(values, field.key)
+		}
+	}
+	records := map[string]json.RawMessage{}
+	for _, child := range children {
+		if len(child.polymorphic) == 0 {
+			continue
+		}
+		if value, ok := values[child.key]; ok {
+			records[child.key] = value
+			delete(values, child.key)
+		}
+	}
+	rest, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(rest, v.Addr().Interface()); err != nil {
+		return err
+	}
+	for _, field := range fields {
+		value, ok := targets[field.key]
+		if !ok || string(value) == "null" {
+			continue
+		}
+		var target struct {
+			Attributes struct {
+				Type string `json:"type"`
+			} `json:"attributes"`
+		}
+		if err := json.Unmarshal(value, &target); err != nil {
+			return err
+		}
+		for _, variant := range field.variants {
+			if variantName(variant) != target.Attributes.Type {
+				continue
+			}
+			decoded := reflect.New(variant)
+			if err := json.Unmarshal(value, decoded.Interface()); err != nil {
+				return err
+			}
+			v.FieldByName(field.name).Set(decoded.Elem())
+			break
+		}
+	}
+	for _, child := range children {
+		value, ok := records[child.key]
+		if !ok || len(child.polymorphic) == 0 || string(value) == "null" {
+			continue
+		}
+		if err := decodeChildRecords(value, v.FieldByName(child.field), child.polymorphic); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeChildRecords decodes the records of a child relationship into the
+// slice v, decoding their polymorphic fields.
+func decodeChildRecords(raw json.RawMessage, v reflect.Value, fields []polymorphicField) error {
+	var records []json.RawMessage
+	if err := json.Unmarshal(raw, &records); err != nil {
+		return err
+	}
+	result := reflect.MakeSlice(v.Type(), len(records), len(records))
+	for i, record := range records {
+		elem := result.Index(i)
+		if elem.Kind() == reflect.Pointer {
+			elem.Set(reflect.New(elem.Type().Elem()))
+			elem = elem.Elem()
+		}
+		if err := decodeRecord(record, elem, fields, nil); err != nil {
+			return err
+		}
+	}
+	v.Set(result)
+	return nil
+}
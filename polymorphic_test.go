@@ -0,0 +1,135 @@
+package sfdc_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/joefitzgerald/sfdc"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+)
+
+type what interface {
+	isWhat()
+}
+
+type Account struct {
+	ID   string `json:"Id"`
+	Name string `json:"Name"`
+}
+
+func (Account) isWhat() {}
+
+type Opportunity struct {
+	ID     string  `json:"Id"`
+	Amount float64 `json:"Amount"`
+}
+
+func (*Opportunity) isWhat() {}
+
+type Task struct {
+	ID   string `json:"Id"`
+	What what   `json:"What"`
+}
+
+func testPolymorphic(t *testing.T, when spec.G, it spec.S) {
+	var (
+		server   *httptest.Server
+		handler  func(w http.ResponseWriter, r *http.Request)
+		instance *sfdc.Instance
+		tasks    *sfdc.Entity[Task]
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		sfdc.RegisterPolymorphic[what](Account{}, &Opportunity{})
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler(w, r)
+		}))
+		var err error
+		instance, err = sfdc.New(sfdc.WithNoAuthentication(), sfdc.WithURL(server.URL))
+		Expect(err).NotTo(HaveOccurred())
+		tasks = sfdc.NewEntity[Task](instance)
+	})
+
+	it.After(func() {
+		server.Close()
+	})
+
+	it("selects the relationship with TYPEOF", func() {
+		Expect(tasks.TaggedFields()).To(Equal("Id,TYPEOF What WHEN Account THEN Id,Name WHEN Opportunity THEN Id,Amount END"))
+	})
+
+	it("decodes each record into the variant for its type", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"done":true,"records":[
+				{"Id":"00T000000000001","What":{"attributes":{"type":"Account"},"Id":"001000000000001","Name":"Acme"}},
+				{"Id":"00T000000000002","What":{"attributes":{"type":"Opportunity"},"Id":"006000000000001","Amount":100}},
+				{"Id":"00T000000000003","What":null}
+			]}`))
+		}
+		result, err := tasks.Query(context.Background(), tasks.Select().String())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal([]Task{
+			{ID: "00T000000000001", What: Account{ID: "001000000000001", Name: "Acme"}},
+			{ID: "00T000000000002", What: &Opportunity{ID: "006000000000001", Amount: 100}},
+			{ID: "00T000000000003"},
+		}))
+	})
+	when("child records have a polymorphic relationship", func() {
+		type Lead struct {
+			ID    string  `json:"Id"`
+			Tasks []*Task `json:"tasks" sfdc:"Tasks"`
+		}
+
+		var (
+			leads *sfdc.Entity[Lead]
+			body  string
+		)
+
+		it.Before(func() {
+			body = `{"done":true,"records":[
+				{"Id":"00Q000000000001","Tasks":{"totalSize":2,"done":true,"records":[
+					{"Id":"00T000000000001","What":{"attributes":{"type":"Account"},"Id":"001000000000001","Name":"Acme"}},
+					{"Id":"00T000000000002","What":{"attributes":{"type":"Opportunity"},"Id":"006000000000001","Amount":100}}
+				]}},
+				{"Id":"00Q000000000002","Tasks":null}
+			]}`
+			leads = sfdc.NewEntity[Lead](instance)
+			leads.SetName("Lead")
+		})
+
+		expected := []Lead{
+			{ID: "00Q000000000001", Tasks: []*Task{
+				{ID: "00T000000000001", What: Account{ID: "001000000000001", Name: "Acme"}},
+				{ID: "00T000000000002", What: &Opportunity{ID: "006000000000001", Amount: 100}},
+			}},
+			{ID: "00Q000000000002"},
+		}
+
+		it("selects the relationship with TYPEOF in the subquery", func() {
+			Expect(leads.TaggedFields()).To(Equal("Id,(SELECT Id,TYPEOF What WHEN Account THEN Id,Name WHEN Opportunity THEN Id,Amount END FROM Tasks)"))
+		})
+
+		it("decodes each child record into the variant for its type", func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(body))
+			}
+			result, err := leads.Query(context.Background(), leads.Select().String())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(expected))
+		})
+
+		it("decodes the child records of a composite query", func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"compositeResponse":[{"body":` + body + `,"httpStatusCode":200,"referenceId":"Leads"}]}`))
+			}
+			composite := instance.NewComposite()
+			result := leads.QueryIn(composite, "Leads", leads.Select().String())
+			Expect(composite.Execute(context.Background())).To(Succeed())
+			Expect(result.Result.Records).To(Equal(expected))
+		})
+	})
+}
@@ -0,0 +1,272 @@
+package sfdc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// QueryOption configures a single query.
+type QueryOption interface {
+	applyToQuery(q *queryConfig)
+}
+
+type queryConfig struct {
+	includeDeleted bool
+	batchSize      int
+	client         string
+	unordered      bool
+}
+
+// setHeaders sets the Sforce-Query-Options and Sforce-Call-Options headers
+// of req.
+func (q *queryConfig) setHeaders(req *http.Request) {
+	if q.batchSize > 0 {
+		req.Header.Set("Sforce-Query-Options", fmt.Sprintf("batchSize=%d", q.batchSize))
+	}
+	if q.client != "" {
+		req.Header.Set("Sforce-Call-Options", fmt.Sprintf("client=%s", q.client))
+	}
+}
+
+type withIncludeDeleted struct {
+	includeDeleted bool
+}
+
+func (w *withIncludeDeleted) applyToQuery(q *queryConfig) {
+	q.includeDeleted = w.includeDeleted
+}
+
+// IncludeDeleted runs a query using the queryAll resource, which returns
+// deleted and archived records.
+func IncludeDeleted() QueryOption {
+	return &withIncludeDeleted{includeDeleted: true}
+}
+
+// ExcludeDeleted runs a query using the query resource, which does not return
+// deleted and archived records.
+func ExcludeDeleted() QueryOption {
+	return &withIncludeDeleted{includeDeleted: false}
+}
+
+type withBatchSize struct {
+	batchSize int
+}
+
+func (w *withBatchSize) applyToQuery(q *queryConfig) {
+	q.batchSize = w.batchSize
+}
+
+// BatchSize requests pages of n records, overriding WithQueryBatchSize.
+// Salesforce accepts 200 to 2000 and treats the size as a hint, returning
+// smaller pages for wide objects or subqueries.
+func BatchSize(n int) QueryOption {
+	return &withBatchSize{batchSize: n}
+}
+
+type withCallClient struct {
+	client string
+}
+
+func (w *withCallClient) applyToQuery(q *queryConfig) {
+	q.client = w.client
+}
+
+// CallClient sets the client name sent in the Sforce-Call-Options header,
+// overriding WithCallClient. Salesforce records it in event logs to
+// attribute API usage.
+func CallClient(client string) QueryOption {
+	return &withCallClient{client: client}
+}
+
+// NotDeleted matches records that are not in the recycle bin. Use it to
+// exclude deleted records while including archived ones with the queryAll
+// resource.
+func NotDeleted() Condition {
+	return Eq("IsDeleted", false)
+}
+
+func (e *Entity[T]) newQueryConfig(options []QueryOption) *queryConfig {
+	config := &queryConfig{
+		includeDeleted: e.includeDeleted,
+		batchSize:      e.instance.queryBatchSize,
+		client:         e.instance.callClient,
+	}
+	for i := range options {
+		options[i].applyToQuery(config)
+	}
+	return config
+}
+
+// queryURI returns the URL that runs query.
+func (e *Entity[T]) queryURI(query string, config *queryConfig) (string, error) {
+	var uri *url.URL
+	var err error
+	if config.includeDeleted {
+		uri, err = e.instance.QueryAllURL()
+	} else {
+		uri, err = e.instance.dataURL("query")
+	}
+	if err != nil {
+		return "", err
+	}
+	q := uri.Query()
+	q.Set("q", query)
+	uri.RawQuery = q.Encode()
+	return uri.String(), nil
+}
+
+// queryPage fetches the page of query results at uri, leaving the records
+// undecoded.
+func (i *Instance) queryPage(ctx context.Context, uri string, config *queryConfig) (*QueryResponse[json.RawMessage], error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	config.setHeaders(req)
+
+	for n := 1; ; n++ {
+		res, last, err := i.doFrom(req, n)
+		if err != nil {
+			return nil, err
+		}
+		r, err := decodeQueryPage(res)
+		if err == nil {
+			return r, nil
+		}
+		// The connection can fail while the body is read, after doFrom has
+		// returned. Fetching a page has no side effects, so fetch it again,
+		// within what is left of the same attempts.
+		n = last
+		delay, ok := i.retryPolicy.retryDelay(req, nil, err, n)
+		if !ok {
+			return nil, err
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// decodeQueryPage reads a page of query results from res and closes it.
+func decodeQueryPage(res *http.Response) (*QueryResponse[json.RawMessage], error) {
+	defer res.Body.Close()
+	if res.StatusCode >= 400 {
+		return nil, errorForResponse(res)
+	}
+	var r QueryResponse[json.RawMessage]
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// nextRecordsURL returns the absolute URL of the page after r.
+func (i *Instance) nextRecordsURL(nextRecordsURL string) string {
+	return fmt.Sprintf("%v%v", i.url, nextRecordsURL)
+}
+
+// fetchPage fetches the page of query results at uri and decodes its records.
+func (e *Entity[T]) fetchPage(ctx context.Context, uri string, config *queryConfig) (*QueryResponse[T], error) {
+	r, err := e.instance.queryPage(ctx, uri, config)
+	if err != nil {
+		return nil, err
+	}
+	records, err := e.decodeRecords(r.Records, func(nextRecordsURL string) (*QueryResponse[json.RawMessage], error) {
+		return e.instance.queryPage(ctx, e.instance.nextRecordsURL(nextRecordsURL), config)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &QueryResponse[T]{
+		Done:           r.Done,
+		NextRecordsURL: r.NextRecordsURL,
+		Records:        records,
+		TotalSize:      r.TotalSize,
+	}, nil
+}
+
+// decodeRecords decodes raw records into T. The results of child
+// relationship subqueries are unwrapped into the slice fields that hold
+// them, calling next to fetch any further pages of child records, and
+// polymorphic relationships are decoded into their registered variants.
+func (e *Entity[T]) decodeRecords(raw []json.RawMessage, next nextPage) ([]T, error) {
+	result := make([]T, len(raw))
+	for i := range raw {
+		rec, err := unwrapChildren(raw[i], e.children, next)
+		if err != nil {
+			return nil, err
+		}
+		if err := decodeRecord(rec, reflect.ValueOf(&result[i]).Elem(), e.polymorphic, e.children); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// nextPage fetches the page of query results at a nextRecordsUrl.
+type nextPage func(nextRecordsURL string) (*QueryResponse[json.RawMessage], error)
+
+// unwrapChildren replaces the subquery results of children in raw, which
+// Salesforce returns under the relationship name, with their records held
+// under the JSON key of the field. Further pages of child records are
+// fetched with next, or left out when next is nil.
+func unwrapChildren(raw json.RawMessage, children []childRelationship, next nextPage) (json.RawMessage, error) {
+	if len(children) == 0 {
+		return raw, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	for _, child := range children {
+		name, ok := lookupKey(fields, child.name)
+		if !ok {
+			continue
+		}
+		value := fields[name]
+		delete(fields, name)
+		if len(value) > 0 && value[0] == '{' {
+			var page QueryResponse[json.RawMessage]
+			if err := json.Unmarshal(value, &page); err != nil {
+				return nil, err
+			}
+			records := page.Records
+			for next != nil && !page.Done && page.NextRecordsURL != "" {
+				more, err := next(page.NextRecordsURL)
+				if err != nil {
+					return nil, err
+				}
+				page = *more
+				records = append(records, page.Records...)
+			}
+			b, err := json.Marshal(records)
+			if err != nil {
+				return nil, err
+			}
+			value = b
+		}
+		fields[child.key] = value
+	}
+	return json.Marshal(fields)
+}
+
+// lookupKey returns the key of fields that matches name, preferring an exact
+// match to one that differs only in case.
+func lookupKey(fields map[string]json.RawMessage, name string) (string, bool) {
+	if _, ok := fields[name]; ok {
+		return name, true
+	}
+	for key := range fields {
+		if strings.EqualFold(key, name) {
+			return key, true
+		}
+	}
+	return "", false
+}
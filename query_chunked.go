@@ -0,0 +1,179 @@
+package sfdc
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// base62Digits are the digits of a record ID in ascending order, which is
+// also the order SOQL compares IDs in.
+const base62Digits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// idLength is the length of the case-sensitive form of a record ID.
+const idLength = 15
+
+// QueryChunked runs the query built by q as up to chunks queries over
+// separate ranges of record IDs, running them concurrently with QueryAsync
+// and writing the records of all of them to the returned channel. The ranges
+// are found by sampling the lowest and highest IDs matching q and splitting
+// the IDs between them evenly, then adding WHERE Id >= x AND Id < y to each
+// query. This avoids the timeouts of a single query over a very large object.
+//
+// Records are written in no particular order, so q should not have ORDER BY,
+// LIMIT or OFFSET clauses. The channel is closed when all records have been
+// written. The first error is written to the returned error channel and
+// stops the remaining queries, as does ctx being done; cancel ctx to stop
+// reading early.
+func (e *Entity[T]) QueryChunked(ctx context.Context, q *QueryBuilder, chunks int, options ...QueryOption) (<-chan []T, <-chan error) {
+	result := make(chan []T)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(result)
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		queries, err := e.chunkQueries(ctx, q, chunks, options)
+		if err != nil {
+			errs <- err
+			return
+		}
+		var once sync.Once
+		fail := func(err error) {
+			once.Do(func() {
+				errs <- err
+				cancel()
+			})
+		}
+		var wg sync.WaitGroup
+		for _, query := range queries {
+			wg.Go(func() {
+				records, chunkErrs := e.QueryAsync(ctx, query, options...)
+				for page := range records {
+					select {
+					case result <- page:
+					case <-ctx.Done():
+					}
+				}
+				select {
+				case err := <-chunkErrs:
+					fail(err)
+				default:
+				}
+			})
+		}
+		wg.Wait()
+	}()
+	return result, errs
+}
+
+// chunkQueries returns the queries over ID ranges that together match the
+// records of q. No queries are returned when q matches no records.
+func (e *Entity[T]) chunkQueries(ctx context.Context, q *QueryBuilder, chunks int, options []QueryOption) ([]string, error) {
+	config := e.newQueryConfig(options)
+	lowest, err := e.boundaryID(ctx, q, Ascending, config)
+	if err != nil || lowest == "" {
+		return nil, err
+	}
+	highest, err := e.boundaryID(ctx, q, Descending, config)
+	if err != nil || highest == "" {
+		return nil, err
+	}
+	boundaries := idBoundaries(lowest, highest, chunks)
+	result := make([]string, 0, len(boundaries)+1)
+	for i := 0; i <= len(boundaries); i++ {
+		chunk := q.clone()
+		if i > 0 {
+			chunk.Where(Ge("Id", boundaries[i-1]))
+		}
+		if i < len(boundaries) {
+			chunk.Where(Lt("Id", boundaries[i]))
+		}
+		result = append(result, chunk.String())
+	}
+	return result, nil
+}
+
+// boundaryID returns the lowest or highest ID of the records matching q, or
+// an empty ID when there are none.
+func (e *Entity[T]) boundaryID(ctx context.Context, q *QueryBuilder, order Order, config *queryConfig) (ID, error) {
+	sample := &QueryBuilder{name: q.name, fields: []string{"Id"}, where: slices.Clone(q.where)}
+	uri, err := e.queryURI(sample.OrderBy("Id", order).Limit(1).String(), config)
+	if err != nil {
+		return "", err
+	}
+	page, err := e.instance.queryPage(ctx, uri, config)
+	if err != nil || len(page.Records) == 0 {
+		return "", err
+	}
+	var record struct {
+		ID ID `json:"Id"`
+	}
+	if err := json.Unmarshal(page.Records[0], &record); err != nil {
+		return "", err
+	}
+	return record.ID, nil
+}
+
+// idBoundaries returns up to chunks-1 IDs that split the range from lowest
+// to highest evenly, in ascending order and each greater than lowest.
+func idBoundaries(lowest ID, highest ID, chunks int) []ID {
+	low, ok := idValue(lowest)
+	if !ok {
+		return nil
+	}
+	high, ok := idValue(highest)
+	if !ok {
+		return nil
+	}
+	span := new(big.Int).Sub(high, low)
+	if span.Sign() <= 0 {
+		return nil
+	}
+	result := []ID{}
+	previous := low
+	for i := 1; i < chunks; i++ {
+		value := new(big.Int).Mul(span, big.NewInt(int64(i)))
+		value.Div(value, big.NewInt(int64(chunks)))
+		value.Add(value, low)
+		if value.Cmp(previous) <= 0 {
+			continue
+		}
+		result = append(result, idFromValue(value))
+		previous = value
+	}
+	return result
+}
+
+// idValue returns the numeric value of the case-sensitive form of id.
+func idValue(id ID) (*big.Int, bool) {
+	if len(id) < idLength {
+		return nil, false
+	}
+	result := new(big.Int)
+	base := big.NewInt(int64(len(base62Digits)))
+	for _, c := range []byte(id[:idLength]) {
+		digit := strings.IndexByte(base62Digits, c)
+		if digit < 0 {
+			return nil, false
+		}
+		result.Mul(result, base)
+		result.Add(result, big.NewInt(int64(digit)))
+	}
+	return result, true
+}
+
+// idFromValue returns the case-sensitive ID with the numeric value v.
+func idFromValue(v *big.Int) ID {
+	b := make([]byte, idLength)
+	value := new(big.Int).Set(v)
+	base := big.NewInt(int64(len(base62Digits)))
+	digit := new(big.Int)
+	for i := idLength - 1; i >= 0; i-- {
+		value.DivMod(value, base, digit)
+		b[i] = base62Digits[digit.Int64()]
+	}
+	return ID(b)
+}
@@ -0,0 +1,123 @@
+package sfdc_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/joefitzgerald/sfdc"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+)
+
+func testQueryChunked(t *testing.T, when spec.G, it spec.S) {
+	type Account struct {
+		ID string `json:"Id"`
+	}
+
+	var (
+		server   *httptest.Server
+		handler  func(w http.ResponseWriter, r *http.Request)
+		accounts *sfdc.Entity[Account]
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler(w, r)
+		}))
+		instance, err := sfdc.New(sfdc.WithNoAuthentication(), sfdc.WithURL(server.URL))
+		Expect(err).NotTo(HaveOccurred())
+		accounts = sfdc.NewEntity[Account](instance)
+	})
+
+	it.After(func() {
+		server.Close()
+	})
+
+	collect := func(records <-chan []Account, errs <-chan error) ([]string, error) {
+		result := []string{}
+		for page := range records {
+			for _, rec := range page {
+				result = append(result, rec.ID)
+			}
+		}
+		select {
+		case err := <-errs:
+			return result, err
+		default:
+			return result, nil
+		}
+	}
+
+	it("splits the query into ID ranges between the lowest and highest IDs", func() {
+		var mu sync.Mutex
+		queries := []string{}
+		responses := map[string]string{
+			"SELECT Id FROM Account WHERE Type = 'Customer' ORDER BY Id ASC LIMIT 1":                                `{"done":true,"records":[{"Id":"001000000000000AAA"}]}`,
+			"SELECT Id FROM Account WHERE Type = 'Customer' ORDER BY Id DESC LIMIT 1":                               `{"done":true,"records":[{"Id":"001000000000400AAA"}]}`,
+			"SELECT Id FROM Account WHERE Type = 'Customer' AND Id < '001000000000100'":                             `{"done":true,"records":[{"Id":"1"}]}`,
+			"SELECT Id FROM Account WHERE Type = 'Customer' AND Id >= '001000000000100' AND Id < '001000000000200'": `{"done":true,"records":[{"Id":"2"}]}`,
+			"SELECT Id FROM Account WHERE Type = 'Customer' AND Id >= '001000000000200' AND Id < '001000000000300'": `{"done":true,"records":[{"Id":"3"}]}`,
+			"SELECT Id FROM Account WHERE Type = 'Customer' AND Id >= '001000000000300'":                            `{"done":true,"records":[{"Id":"4"}]}`,
+		}
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query().Get("q")
+			mu.Lock()
+			queries = append(queries, q)
+			mu.Unlock()
+			body, ok := responses[q]
+			if !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`[{"message":"unexpected query","errorCode":"MALFORMED_QUERY"}]`))
+				return
+			}
+			w.Write([]byte(body))
+		}
+		q := accounts.Select().Where(sfdc.Eq("Type", "Customer"))
+		result, err := collect(accounts.QueryChunked(context.Background(), q, 4))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(ConsistOf("1", "2", "3", "4"))
+		Expect(queries).To(HaveLen(6))
+		Expect(q.String()).To(Equal("SELECT Id FROM Account WHERE Type = 'Customer'"))
+	})
+
+	it("runs a single query when the lowest and highest IDs are the same", func() {
+		queries := []string{}
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			queries = append(queries, r.URL.Query().Get("q"))
+			w.Write([]byte(`{"done":true,"records":[{"Id":"001000000000000AAA"}]}`))
+		}
+		result, err := collect(accounts.QueryChunked(context.Background(), accounts.Select(), 4))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal([]string{"001000000000000AAA"}))
+		Expect(queries).To(ContainElement("SELECT Id FROM Account"))
+	})
+
+	it("returns no records when none match", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"done":true,"records":[]}`))
+		}
+		result, err := collect(accounts.QueryChunked(context.Background(), accounts.Select(), 4))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(BeEmpty())
+	})
+
+	it("reports the error of a failed chunk", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Query().Get("q") {
+			case "SELECT Id FROM Account ORDER BY Id ASC LIMIT 1":
+				w.Write([]byte(`{"done":true,"records":[{"Id":"001000000000000AAA"}]}`))
+			case "SELECT Id FROM Account ORDER BY Id DESC LIMIT 1":
+				w.Write([]byte(`{"done":true,"records":[{"Id":"001000000000400AAA"}]}`))
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`[{"message":"query timed out","errorCode":"QUERY_TIMEOUT"}]`))
+			}
+		}
+		_, err := collect(accounts.QueryChunked(context.Background(), accounts.Select(), 2))
+		Expect(err).To(MatchError(ContainSubstring("QUERY_TIMEOUT")))
+	})
+}
@@ -0,0 +1,172 @@
+package sfdc
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type withUnordered struct{}
+
+func (w *withUnordered) applyToQuery(q *queryConfig) {
+	q.unordered = true
+}
+
+// Unordered lets QueryParallel yield pages as soon as they are fetched,
+// rather than in the order of the query.
+func Unordered() QueryOption {
+	return &withUnordered{}
+}
+
+// QueryParallel runs query and returns an iterator over its pages of records
+// like Pages, fetching up to workers pages at a time. After the first page,
+// the offset of every remaining page is computed from the query locator and
+// the total size of the results, so the pages can be fetched concurrently.
+// Pages are yielded in order unless the Unordered option is given. Breaking
+// out of the loop cancels the fetches in progress, and an error ends the
+// iteration.
+func (e *Entity[T]) QueryParallel(ctx context.Context, query string, workers int, options ...QueryOption) iter.Seq2[[]T, error] {
+	return func(yield func([]T, error) bool) {
+		config := e.newQueryConfig(options)
+		uri, err := e.queryURI(query, config)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		first, err := e.fetchPage(ctx, uri, config)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		if !yield(first.Records, nil) || first.Cursor() == "" {
+			return
+		}
+		locator, step, ok := splitLocator(first.Cursor())
+		if !ok {
+			// Fall back to fetching the remaining pages one at a time.
+			e.responses(ctx, e.instance.nextRecordsURL(string(first.Cursor())), config, func(page *QueryResponse[T], err error) bool {
+				if err != nil {
+					return yield(nil, err)
+				}
+				return yield(page.Records, nil)
+			})
+			return
+		}
+		uris := []string{}
+		for offset := step; offset < first.TotalSize; offset += step {
+			uris = append(uris, e.instance.nextRecordsURL(fmt.Sprintf("%s-%d", locator, offset)))
+		}
+		e.fetchPages(ctx, uris, workers, config, yield)
+	}
+}
+
+// splitLocator splits a cursor of the form {locator}-{offset} into the
+// locator and offset.
+func splitLocator(cursor Cursor) (string, int, bool) {
+	s := string(cursor)
+	i := strings.LastIndex(s, "-")
+	if i < 0 {
+		return "", 0, false
+	}
+	offset, err := strconv.Atoi(s[i+1:])
+	if err != nil || offset <= 0 {
+		return "", 0, false
+	}
+	return s[:i], offset, true
+}
+
+// fetchPages fetches the pages at uris using up to workers goroutines,
+// passing their records to yield until it returns false. No more than
+// workers pages are fetched ahead of the next page to be yielded, so that
+// a slow page does not leave the pages after it held in memory.
+func (e *Entity[T]) fetchPages(ctx context.Context, uris []string, workers int, config *queryConfig, yield func([]T, error) bool) {
+	type result struct {
+		index   int
+		records []T
+		err     error
+	}
+	workers = min(max(workers, 1), len(uris))
+	// fetchCtx is also cancelled when the caller stops iterating, which
+	// must not be mistaken for ctx being cancelled.
+	fetchCtx, cancel := context.WithCancel(ctx)
+	jobs := make(chan int)
+	results := make(chan result)
+	// window holds a value for each page fetched but not yet yielded.
+	window := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Go(func() {
+			for index := range jobs {
+				r := result{index: index}
+				page, err := e.fetchPage(fetchCtx, uris[index], config)
+				if err != nil {
+					r.err = err
+				} else {
+					r.records = page.Records
+				}
+				// Results are always received, either below or while
+				// draining, so every error is delivered.
+				results <- r
+			}
+		})
+	}
+	go func() {
+		defer close(jobs)
+		for index := range uris {
+			select {
+			case window <- struct{}{}:
+			case <-fetchCtx.Done():
+				return
+			}
+			select {
+			case jobs <- index:
+			case <-fetchCtx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	defer func() {
+		cancel()
+		for range results {
+		}
+	}()
+
+	// next counts the pages yielded, which in order is the index of the
+	// next page to yield.
+	pending := map[int][]T{}
+	next := 0
+	for r := range results {
+		if r.err != nil {
+			yield(nil, r.err)
+			return
+		}
+		if config.unordered {
+			next++
+			<-window
+			if !yield(r.records, nil) {
+				return
+			}
+			continue
+		}
+		pending[r.index] = r.records
+		for records, ok := pending[next]; ok; records, ok = pending[next] {
+			delete(pending, next)
+			next++
+			<-window
+			if !yield(records, nil) {
+				return
+			}
+		}
+	}
+	if next < len(uris) {
+		// The pages stopped being fetched because ctx is done.
+		yield(nil, ctx.Err())
+	}
+}
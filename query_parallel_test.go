@@ -0,0 +1,179 @@
+package sfdc_test
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/joefitzgerald/sfdc"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+)
+
+func testQueryParallel(t *testing.T, when spec.G, it spec.S) {
+	type Account struct {
+		ID string `json:"Id"`
+	}
+
+	var (
+		server   *httptest.Server
+		handler  func(w http.ResponseWriter, r *http.Request)
+		accounts *sfdc.Entity[Account]
+		calls    atomic.Int32
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		calls.Store(0)
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			switch r.URL.Path {
+			case "/services/data/v54.0/queryAll":
+				w.Write([]byte(`{"totalSize":5,"done":false,"nextRecordsUrl":"/services/data/v54.0/query/01g-2","records":[{"Id":"1"},{"Id":"2"}]}`))
+			case "/services/data/v54.0/query/01g-2":
+				// Finish after the later pages to check that order is kept.
+				time.Sleep(50 * time.Millisecond)
+				w.Write([]byte(`{"totalSize":5,"done":false,"nextRecordsUrl":"/services/data/v54.0/query/01g-4","records":[{"Id":"3"},{"Id":"4"}]}`))
+			case "/services/data/v54.0/query/01g-4":
+				w.Write([]byte(`{"totalSize":5,"done":true,"records":[{"Id":"5"}]}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`[{"message":"not found","errorCode":"NOT_FOUND"}]`))
+			}
+		}
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler(w, r)
+		}))
+		instance, err := sfdc.New(sfdc.WithNoAuthentication(), sfdc.WithURL(server.URL))
+		Expect(err).NotTo(HaveOccurred())
+		accounts = sfdc.NewEntity[Account](instance)
+	})
+
+	it.After(func() {
+		server.Close()
+	})
+
+	ids := func(pages iter.Seq2[[]Account, error]) []string {
+		result := []string{}
+		for page, err := range pages {
+			Expect(err).NotTo(HaveOccurred())
+			for _, rec := range page {
+				result = append(result, rec.ID)
+			}
+		}
+		return result
+	}
+
+	it("fetches the pages computed from the locator in order", func() {
+		result := ids(accounts.QueryParallel(context.Background(), "SELECT Id FROM Account", 4))
+		Expect(result).To(Equal([]string{"1", "2", "3", "4", "5"}))
+		Expect(calls.Load()).To(BeEquivalentTo(3))
+	})
+
+	it("yields pages as they are fetched when unordered", func() {
+		result := ids(accounts.QueryParallel(context.Background(), "SELECT Id FROM Account", 4, sfdc.Unordered()))
+		Expect(result).To(Equal([]string{"1", "2", "5", "3", "4"}))
+	})
+
+	it("returns a single page without fetching more", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.Write([]byte(`{"totalSize":1,"done":true,"records":[{"Id":"1"}]}`))
+		}
+		result := ids(accounts.QueryParallel(context.Background(), "SELECT Id FROM Account", 4))
+		Expect(result).To(Equal([]string{"1"}))
+		Expect(calls.Load()).To(BeEquivalentTo(1))
+	})
+
+	it("stops after a break", func() {
+		count := 0
+		for _, err := range accounts.QueryParallel(context.Background(), "SELECT Id FROM Account", 2) {
+			Expect(err).NotTo(HaveOccurred())
+			count++
+			if count == 2 {
+				break
+			}
+		}
+		Expect(count).To(Equal(2))
+	})
+
+	it("yields the error of a failed page", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/services/data/v54.0/queryAll" {
+				w.Write([]byte(`{"totalSize":4,"done":false,"nextRecordsUrl":"/services/data/v54.0/query/01g-2","records":[{"Id":"1"},{"Id":"2"}]}`))
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`[{"message":"invalid query locator","errorCode":"INVALID_QUERY_LOCATOR"}]`))
+		}
+		var last error
+		for _, err := range accounts.QueryParallel(context.Background(), "SELECT Id FROM Account", 2) {
+			last = err
+		}
+		Expect(last).To(MatchError(ContainSubstring("INVALID_QUERY_LOCATOR")))
+	})
+
+	it("yields an error when ctx is cancelled partway through", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/services/data/v54.0/queryAll" {
+				w.Write([]byte(`{"totalSize":8,"done":false,"nextRecordsUrl":"/services/data/v54.0/query/01g-2","records":[{"Id":"1"},{"Id":"2"}]}`))
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+			w.Write([]byte(`{"totalSize":8,"done":false,"records":[{"Id":"3"},{"Id":"4"}]}`))
+		}
+		for range 20 {
+			ctx, cancel := context.WithCancel(context.Background())
+			pages := 0
+			var last error
+			for _, err := range accounts.QueryParallel(ctx, "SELECT Id FROM Account", 1) {
+				if err != nil {
+					last = err
+					continue
+				}
+				pages++
+				if pages == 2 {
+					cancel()
+				}
+			}
+			cancel()
+			Expect(pages).To(BeNumerically("<", 4))
+			Expect(last).To(MatchError(context.Canceled))
+		}
+	})
+
+	it("fetches no more than workers pages ahead of the next page in order", func() {
+		release := make(chan struct{})
+		var requested sync.Map
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			requested.Store(r.URL.Path, true)
+			switch r.URL.Path {
+			case "/services/data/v54.0/queryAll":
+				w.Write([]byte(`{"totalSize":10,"done":false,"nextRecordsUrl":"/services/data/v54.0/query/01g-2","records":[{"Id":"1"},{"Id":"2"}]}`))
+				return
+			case "/services/data/v54.0/query/01g-2":
+				<-release
+			}
+			w.Write([]byte(`{"totalSize":10,"done":false,"records":[{"Id":"x"},{"Id":"y"}]}`))
+		}
+		done := make(chan []string)
+		go func() {
+			done <- ids(accounts.QueryParallel(context.Background(), "SELECT Id FROM Account", 2))
+		}()
+		Eventually(func() bool {
+			_, ok := requested.Load("/services/data/v54.0/query/01g-4")
+			return ok
+		}).Should(BeTrue())
+		Consistently(func() bool {
+			_, ok := requested.Load("/services/data/v54.0/query/01g-6")
+			return ok
+		}, 100*time.Millisecond).Should(BeFalse())
+		close(release)
+		Eventually(done).Should(Receive(HaveLen(10)))
+	})
+}
@@ -0,0 +1,74 @@
+package sfdc
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// record is the JSON object sent to Salesforce when writing an sObject.
+type record map[string]json.RawMessage
+
+// newRecord encodes v and removes the keys in readOnly, along with the record
+// Id unless keepID is set. Zero time.Time and Date fields are removed, as
+// omitempty has no effect on structs.
+func newRecord(v any, readOnly map[string]bool, keepID bool) (record, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var result record
+	if err := json.Unmarshal(b, &result); err != nil {
+		return nil, err
+	}
+	for key := range result {
+		if readOnly[key] || key == "attributes" || (!keepID && strings.EqualFold(key, "Id")) {
+			delete(result, key)
+		}
+	}
+	for _, key := range zeroTimeKeys(reflect.ValueOf(v)) {
+		delete(result, key)
+	}
+	return result, nil
+}
+
+var (
+	timeType = reflect.TypeFor[time.Time]()
+	dateType = reflect.TypeFor[Date]()
+)
+
+// zeroTimeKeys returns the JSON keys of the time.Time and Date fields of v
+// that hold the zero time.
+func zeroTimeKeys(v reflect.Value) []string {
+	v = reflect.Indirect(v)
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	result := []string{}
+	for _, field := range deepFields(v.Type()) {
+		if !field.IsExported() || field.Type != timeType && field.Type != dateType {
+			continue
+		}
+		name, ok := jsonName(field)
+		if !ok {
+			continue
+		}
+		value := v.FieldByName(field.Name).Convert(timeType).Interface().(time.Time)
+		if value.IsZero() {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// setAttributes sets the attributes object Salesforce uses to identify the
+// type (and for some APIs the reference ID) of a record.
+func (r record) setAttributes(attributes map[string]string) error {
+	b, err := json.Marshal(attributes)
+	if err != nil {
+		return err
+	}
+	r["attributes"] = b
+	return nil
+}
@@ -0,0 +1,135 @@
+package sfdc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// do sends req using the instance's HTTP client. Every request made by the
+// package flows through here.
+func (i *Instance) do(req *http.Request) (*http.Response, error) {
+	res, _, err := i.doFrom(req, 1)
+	return res, err
+}
+
+// doFrom sends req as in do, counting the first send as attempt first of the
+// retry policy's MaxAttempts. It returns the number of the last attempt, so
+// that a caller that retries after reading the response can share the same
+// budget.
+func (i *Instance) doFrom(req *http.Request, first int) (*http.Response, int, error) {
+	attempt := req
+	for n := first; ; n++ {
+		release, err := i.acquire(req.Context())
+		if err != nil {
+			return nil, n, err
+		}
+		res, err := i.doWithSession(attempt)
+		res = releaseOnClose(res, release)
+		i.recordUsage(res)
+		delay, ok := i.retryPolicy.retryDelay(req, res, err, n)
+		if !ok {
+			return res, n, err
+		}
+		next, rewindErr := rewind(req)
+		if rewindErr != nil {
+			return res, n, err
+		}
+		if res != nil {
+			res.Body.Close()
+		}
+		if err := sleep(req.Context(), delay); err != nil {
+			return nil, n, err
+		}
+		attempt = next
+	}
+}
+
+// doWithSession sends req, refreshing the token and retrying once when
+// Salesforce reports that the session is no longer valid.
+func (i *Instance) doWithSession(req *http.Request) (*http.Response, error) {
+	if i.session == nil {
+		return i.client.Do(req)
+	}
+	accessToken := i.session.current()
+	res, err := i.client.Do(req)
+	if err != nil || res.StatusCode != http.StatusUnauthorized {
+		return res, err
+	}
+	// The session was revoked or expired early, so refresh the token and
+	// retry once.
+	body, err := peekBody(res)
+	if err != nil {
+		return nil, err
+	}
+	if !parseAPIError(res.StatusCode, body).HasErrorCode("INVALID_SESSION_ID") {
+		return res, nil
+	}
+	retry, err := rewind(req)
+	if err != nil {
+		return res, nil
+	}
+	i.session.invalidate(accessToken)
+	return i.client.Do(retry)
+}
+
+// rewind returns a copy of req that can be sent again.
+func rewind(req *http.Request) (*http.Request, error) {
+	result := req.Clone(req.Context())
+	if req.Body == nil || req.Body == http.NoBody {
+		return result, nil
+	}
+	if req.GetBody == nil {
+		return nil, errors.New("request body cannot be sent again")
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	result.Body = body
+	return result, nil
+}
+
+// send issues a JSON request to uri. The body, when non-nil, is encoded as
+// JSON and the response is decoded into out when out is non-nil. The HTTP
+// status code is returned so callers can distinguish successful outcomes.
+func (i *Instance) send(ctx context.Context, method string, uri string, body any, out any) (int, error) {
+	res, err := i.sendJSON(ctx, method, uri, body)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 400 {
+		return res.StatusCode, errorForResponse(res)
+	}
+	if out == nil || res.StatusCode == http.StatusNoContent {
+		return res.StatusCode, nil
+	}
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return res.StatusCode, err
+	}
+	return res.StatusCode, nil
+}
+
+// sendJSON issues a JSON request to uri and returns the response, which the
+// caller must close.
+func (i *Instance) sendJSON(ctx context.Context, method string, uri string, body any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, uri, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	return i.do(req)
+}
@@ -1,12 +1,5 @@
 package sfdc
 
-import (
-	"encoding/json"
-	"errors"
-	"fmt"
-	"io"
-)
-
 type QueryResponse[T any] struct {
 	Done           bool   `json:"done" sfdc:"-"`
 	NextRecordsURL string `json:"nextRecordsUrl" sfdc:"-"`
@@ -14,13 +7,32 @@ type QueryResponse[T any] struct {
 	TotalSize      int    `json:"totalSize" sfdc:"-"`
 }
 
-func errorForResponse(r io.Reader) error {
-	var errorMessage []struct {
-		Message   string `json:"message"`
-		ErrorCode string `json:"errorCode"`
-	}
-	if err := json.NewDecoder(r).Decode(&errorMessage); err != nil {
-		return errors.New("got bad result")
+// Cursor is the locator of the next page of query results. It is a plain
+// string, so it can be saved after each page and passed to Entity.Resume to
+// continue the query later. Salesforce expires a locator after about 15
+// minutes of inactivity, and after two days regardless.
+type Cursor string
+
+// Cursor returns the locator of the page after r, or an empty Cursor when r
+// is the last page.
+func (r *QueryResponse[T]) Cursor() Cursor {
+	if r.Done {
+		return ""
 	}
-	return fmt.Errorf("%s (%s)", errorMessage[0].Message, errorMessage[0].ErrorCode)
+	return Cursor(r.NextRecordsURL)
+}
+
+// SaveResult reports the outcome of writing a single record.
+type SaveResult struct {
+	ID      ID          `json:"id"`
+	Success bool        `json:"success"`
+	Created bool        `json:"created"`
+	Errors  []SaveError `json:"errors"`
+}
+
+// SaveError describes why a record could not be written.
+type SaveError struct {
+	StatusCode string   `json:"statusCode"`
+	Message    string   `json:"message"`
+	Fields     []string `json:"fields"`
 }
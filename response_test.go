@@ -1,6 +1,10 @@
 package sfdc
 
 import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
 	"strings"
 	"testing"
 
@@ -13,15 +17,73 @@ func testResponse(t *testing.T, when spec.G, it spec.S) {
 		RegisterTestingT(t)
 	})
 
+	response := func(status int, body string) *http.Response {
+		uri, _ := url.Parse("https://example.my.salesforce.com/services/data/v54.0/query")
+		return &http.Response{
+			StatusCode: status,
+			Header:     http.Header{"Sforce-Limit-Info": []string{"api-usage=25/15000"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    &http.Request{URL: uri},
+		}
+	}
+
 	when("something goes wrong", func() {
 		it("returns an error", func() {
-			response := strings.NewReader(`[
+			err := errorForResponse(response(http.StatusBadRequest, `[
 				{"message": "test error!", "errorCode": "ERR_TEST"}
-			]`)
-
-			err := errorForResponse(response)
+			]`))
 			Expect(err.Error()).To(ContainSubstring("test error"))
 			Expect(err.Error()).To(ContainSubstring("ERR_TEST"))
 		})
+
+		it("returns an APIError describing the response", func() {
+			err := errorForResponse(response(http.StatusBadRequest, `[
+				{"message": "no such column", "errorCode": "INVALID_FIELD", "fields": ["Foo__c"]},
+				{"message": "unexpected token", "errorCode": "MALFORMED_QUERY"}
+			]`))
+			var apiErr *APIError
+			Expect(errors.As(err, &apiErr)).To(BeTrue())
+			Expect(apiErr.StatusCode).To(Equal(http.StatusBadRequest))
+			Expect(apiErr.URL).To(Equal("https://example.my.salesforce.com/services/data/v54.0/query"))
+			Expect(apiErr.LimitInfo).To(Equal("api-usage=25/15000"))
+			Expect(apiErr.Errors).To(Equal([]ErrorDetail{
+				{Message: "no such column", ErrorCode: "INVALID_FIELD", Fields: []string{"Foo__c"}},
+				{Message: "unexpected token", ErrorCode: "MALFORMED_QUERY"},
+			}))
+			Expect(err.Error()).To(Equal("no such column (INVALID_FIELD); unexpected token (MALFORMED_QUERY)"))
+		})
+
+		it("matches sentinel errors by error code", func() {
+			err := errorForResponse(response(http.StatusUnauthorized, `[{"message": "Session expired or invalid", "errorCode": "INVALID_SESSION_ID"}]`))
+			Expect(errors.Is(err, ErrInvalidSession)).To(BeTrue())
+			Expect(errors.Is(err, ErrMalformedQuery)).To(BeFalse())
+			Expect(errors.Is(err, ErrRequestLimitExceeded)).To(BeFalse())
+		})
+
+		it("handles an empty list of errors", func() {
+			err := errorForResponse(response(http.StatusInternalServerError, `[]`))
+			var apiErr *APIError
+			Expect(errors.As(err, &apiErr)).To(BeTrue())
+			Expect(apiErr.Errors).To(BeEmpty())
+			Expect(err.Error()).To(Equal("unexpected response: 500 Internal Server Error"))
+		})
+
+		it("keeps a body that is not JSON", func() {
+			err := errorForResponse(response(http.StatusServiceUnavailable, `<html><body>Down for maintenance</body></html>`))
+			var apiErr *APIError
+			Expect(errors.As(err, &apiErr)).To(BeTrue())
+			Expect(apiErr.Body).To(ContainSubstring("Down for maintenance"))
+			Expect(err.Error()).To(Equal("unexpected response: 503 Service Unavailable"))
+		})
+
+		it("reads a single error object", func() {
+			err := errorForResponse(response(http.StatusBadRequest, `{"errorCode": "INVALIDJOB", "message": "job not found"}`))
+			Expect(err.Error()).To(Equal("job not found (INVALIDJOB)"))
+		})
+
+		it("reads an OAuth error", func() {
+			err := errorForResponse(response(http.StatusBadRequest, `{"error": "invalid_grant", "error_description": "expired access/refresh token"}`))
+			Expect(err.Error()).To(Equal("expired access/refresh token (invalid_grant)"))
+		})
 	})
 }
@@ -0,0 +1,150 @@
+package sfdc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy controls how requests that fail for transient reasons are
+// retried. Zero fields take their default values.
+type RetryPolicy struct {
+	// MaxAttempts is the most times a request is sent, including the first.
+	// The default is 3.
+	MaxAttempts int
+	// InitialDelay is the delay before the first retry, which doubles with
+	// each retry after it. The default is 500ms.
+	InitialDelay time.Duration
+	// MaxDelay is the longest delay between attempts, unless the response
+	// asks for a longer one with Retry-After. The default is 30s.
+	MaxDelay time.Duration
+}
+
+type withRetryPolicy struct {
+	policy RetryPolicy
+}
+
+func (w *withRetryPolicy) applyToInstance(i *Instance) {
+	policy := w.policy
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 3
+	}
+	if policy.InitialDelay <= 0 {
+		policy.InitialDelay = 500 * time.Millisecond
+	}
+	if policy.MaxDelay <= 0 {
+		policy.MaxDelay = 30 * time.Second
+	}
+	i.retryPolicy = &policy
+}
+
+// WithRetryPolicy retries requests that fail for transient reasons, waiting
+// between attempts with jittered exponential backoff, or as long as the
+// Retry-After header of the response asks. Idempotent requests are retried
+// on 502 and 503 responses, SERVER_UNAVAILABLE errors, connection resets and
+// timeouts. Any request is retried on UNABLE_TO_LOCK_ROW, as Salesforce
+// rejects the request without applying it.
+func WithRetryPolicy(policy RetryPolicy) InstanceOption {
+	return &withRetryPolicy{policy: policy}
+}
+
+// retryDelay returns how long to wait before sending req again, and whether
+// it should be sent again, after attempt ended with res or err.
+func (p *RetryPolicy) retryDelay(req *http.Request, res *http.Response, err error, attempt int) (time.Duration, bool) {
+	if p == nil || attempt >= p.MaxAttempts || req.Context().Err() != nil {
+		return 0, false
+	}
+	if err != nil {
+		return p.backoff(attempt), isIdempotent(req) && isTransient(err)
+	}
+	if res.StatusCode < 400 {
+		return 0, false
+	}
+	retry := false
+	switch res.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		retry = isIdempotent(req)
+	}
+	if !retry {
+		body, err := peekBody(res)
+		if err != nil {
+			return 0, false
+		}
+		apiErr := parseAPIError(res.StatusCode, body)
+		retry = apiErr.HasErrorCode("UNABLE_TO_LOCK_ROW") ||
+			isIdempotent(req) && apiErr.HasErrorCode("SERVER_UNAVAILABLE")
+	}
+	if !retry {
+		return 0, false
+	}
+	if delay, ok := retryAfter(res); ok {
+		return delay, true
+	}
+	return p.backoff(attempt), true
+}
+
+// backoff returns a random delay of between half and all of the
+// exponential delay for attempt.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.InitialDelay
+	for n := 1; n < attempt && delay < p.MaxDelay; n++ {
+		delay *= 2
+	}
+	delay = min(delay, p.MaxDelay)
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// retryAfter returns the delay asked for by the Retry-After header of res,
+// given either in seconds or as a date.
+func retryAfter(res *http.Response) (time.Duration, bool) {
+	value := res.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
+
+// isIdempotent reports whether sending req more than once has the same
+// effect as sending it once.
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// isTransient reports whether err is a network failure that may not recur.
+func isTransient(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// peekBody reads the body of res, replacing it so that it can be read again.
+func peekBody(res *http.Response) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxErrorBody))
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
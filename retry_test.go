@@ -0,0 +1,220 @@
+package sfdc_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/joefitzgerald/sfdc"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+)
+
+func testRetry(t *testing.T, when spec.G, it spec.S) {
+	type Account struct {
+		ID   string `json:"Id,omitempty"`
+		Name string `json:"Name,omitempty"`
+	}
+
+	var (
+		server   *httptest.Server
+		handler  func(w http.ResponseWriter, r *http.Request)
+		accounts *sfdc.Entity[Account]
+		calls    atomic.Int32
+		policy   sfdc.RetryPolicy
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		calls.Store(0)
+		policy = sfdc.RetryPolicy{MaxAttempts: 3, InitialDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			handler(w, r)
+		}))
+	})
+
+	it.After(func() {
+		server.Close()
+	})
+
+	newAccounts := func() {
+		instance, err := sfdc.New(sfdc.WithNoAuthentication(), sfdc.WithURL(server.URL), sfdc.WithRetryPolicy(policy))
+		Expect(err).NotTo(HaveOccurred())
+		accounts = sfdc.NewEntity[Account](instance)
+	}
+
+	unavailable := func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`<html><body>Service Unavailable</body></html>`))
+	}
+
+	it("retries an idempotent request until it succeeds", func() {
+		newAccounts()
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			if calls.Load() < 3 {
+				unavailable(w)
+				return
+			}
+			w.Write([]byte(`{"Id":"001","Name":"Acme"}`))
+		}
+		account, err := accounts.Get(context.Background(), "001")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(account.Name).To(Equal("Acme"))
+		Expect(calls.Load()).To(BeEquivalentTo(3))
+	})
+
+	it("gives up after the maximum number of attempts", func() {
+		policy.MaxAttempts = 2
+		newAccounts()
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			unavailable(w)
+		}
+		_, err := accounts.Get(context.Background(), "001")
+		var apiErr *sfdc.APIError
+		Expect(errors.As(err, &apiErr)).To(BeTrue())
+		Expect(apiErr.StatusCode).To(Equal(http.StatusServiceUnavailable))
+		Expect(calls.Load()).To(BeEquivalentTo(2))
+	})
+
+	it("does not retry a POST that may have been applied", func() {
+		newAccounts()
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+		_, err := accounts.Create(context.Background(), &Account{Name: "Acme"})
+		Expect(err).To(HaveOccurred())
+		Expect(calls.Load()).To(BeEquivalentTo(1))
+	})
+
+	it("retries any request when the row is locked, resending the body", func() {
+		newAccounts()
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			if calls.Load() == 1 {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`[{"message":"unable to obtain exclusive access to this record","errorCode":"UNABLE_TO_LOCK_ROW"}]`))
+				return
+			}
+			var body map[string]any
+			Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+			Expect(body).To(Equal(map[string]any{"Name": "Acme"}))
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"001","success":true}`))
+		}
+		id, err := accounts.Create(context.Background(), &Account{Name: "Acme"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(id).To(Equal(sfdc.ID("001")))
+		Expect(calls.Load()).To(BeEquivalentTo(2))
+	})
+
+	it("waits as long as Retry-After asks rather than backing off", func() {
+		policy.InitialDelay = time.Hour
+		policy.MaxDelay = time.Hour
+		newAccounts()
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			if calls.Load() == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				w.Write([]byte(`[{"message":"server unavailable","errorCode":"SERVER_UNAVAILABLE"}]`))
+				return
+			}
+			w.Write([]byte(`{"Id":"001"}`))
+		}
+		_, err := accounts.Get(context.Background(), "001")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(calls.Load()).To(BeEquivalentTo(2))
+	})
+
+	it("retries after the connection is reset", func() {
+		newAccounts()
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			if calls.Load() == 1 {
+				conn, _, err := http.NewResponseController(w).Hijack()
+				Expect(err).NotTo(HaveOccurred())
+				conn.Close()
+				return
+			}
+			w.Write([]byte(`{"Id":"001"}`))
+		}
+		_, err := accounts.Get(context.Background(), "001")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(calls.Load()).To(BeEquivalentTo(2))
+	})
+
+	it("retries a page fetched in the middle of a query", func() {
+		newAccounts()
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.URL.Path == "/services/data/v54.0/queryAll":
+				w.Write([]byte(`{"done":false,"nextRecordsUrl":"/next","records":[{"Id":"1"}]}`))
+			case calls.Load() == 2:
+				unavailable(w)
+			default:
+				w.Write([]byte(`{"done":true,"records":[{"Id":"2"}]}`))
+			}
+		}
+		records, errs := accounts.QueryAsync(context.Background(), "SELECT Id FROM Account")
+		ids := []string{}
+		for page := range records {
+			for _, rec := range page {
+				ids = append(ids, rec.ID)
+			}
+		}
+		Expect(errs).To(BeEmpty())
+		Expect(ids).To(Equal([]string{"1", "2"}))
+	})
+
+	it("retries a page whose body is cut off", func() {
+		newAccounts()
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.URL.Path == "/services/data/v54.0/queryAll":
+				w.Write([]byte(`{"done":false,"nextRecordsUrl":"/next","records":[{"Id":"1"}]}`))
+			case calls.Load() == 2:
+				body := `{"done":true,"records":[{"Id":"2"}]}`
+				w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+				w.Write([]byte(body[:len(body)/2]))
+			default:
+				w.Write([]byte(`{"done":true,"records":[{"Id":"2"}]}`))
+			}
+		}
+		result, err := accounts.Query(context.Background(), "SELECT Id FROM Account")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal([]Account{{ID: "1"}, {ID: "2"}}))
+		Expect(calls.Load()).To(BeEquivalentTo(3))
+	})
+
+	it("counts a page whose body is cut off against the same attempts", func() {
+		newAccounts()
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			if calls.Load() < 3 {
+				unavailable(w)
+				return
+			}
+			body := `{"done":true,"records":[{"Id":"1"}]}`
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			w.Write([]byte(body[:len(body)/2]))
+		}
+		_, err := accounts.Query(context.Background(), "SELECT Id FROM Account")
+		Expect(err).To(HaveOccurred())
+		Expect(calls.Load()).To(BeEquivalentTo(policy.MaxAttempts))
+	})
+
+	it("does not retry without a policy", func() {
+		instance, err := sfdc.New(sfdc.WithNoAuthentication(), sfdc.WithURL(server.URL))
+		Expect(err).NotTo(HaveOccurred())
+		accounts = sfdc.NewEntity[Account](instance)
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			unavailable(w)
+		}
+		_, err = accounts.Get(context.Background(), "001")
+		Expect(err).To(HaveOccurred())
+		Expect(calls.Load()).To(BeEquivalentTo(1))
+	})
+}
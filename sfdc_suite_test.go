@@ -15,6 +15,19 @@ func init() {
 	suite("entity", testEntity)
 	suite("auth options", testAuthOptions)
 	suite("fields", testFields)
+	suite("collections", testCollections)
+	suite("composite", testComposite)
+	suite("graph", testGraph)
+	suite("tree", testTree)
+	suite("bulk", testBulk)
+	suite("soql", testSOQL)
+	suite("polymorphic", testPolymorphic)
+	suite("query parallel", testQueryParallel)
+	suite("query chunked", testQueryChunked)
+	suite("retry", testRetry)
+	suite("usage", testUsage)
+	suite("limit", testLimit)
+	suite("governor", testGovernor)
 }
 
 func Test(t *testing.T) {
@@ -0,0 +1,422 @@
+package sfdc
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Order is the direction of an ORDER BY clause.
+type Order string
+
+const (
+	Ascending  Order = "ASC"
+	Descending Order = "DESC"
+)
+
+// QueryBuilder builds a SOQL query for an Entity. Field names are written to
+// the query as given, while values in conditions are formatted as SOQL
+// literals and escaped.
+type QueryBuilder struct {
+	name    string
+	fields  []string
+	where   []Condition
+	groupBy []string
+	having  []Condition
+	orderBy []string
+	limit   int
+	offset  int
+	scope   string
+}
+
+// Select starts a query for the entity. The fields default to TaggedFields
+// when none are given.
+func (e *Entity[T]) Select(fields ...string) *QueryBuilder {
+	if len(fields) == 0 {
+		fields = []string{e.taggedFields}
+	}
+	return &QueryBuilder{name: e.name, fields: fields}
+}
+
+// clone returns a copy of q that can be changed without affecting q.
+func (q *QueryBuilder) clone() *QueryBuilder {
+	result := *q
+	result.fields = slices.Clone(q.fields)
+	result.where = slices.Clone(q.where)
+	result.groupBy = slices.Clone(q.groupBy)
+	result.having = slices.Clone(q.having)
+	result.orderBy = slices.Clone(q.orderBy)
+	return &result
+}
+
+// Where adds conditions that records must meet. Conditions from every call
+// are combined with AND.
+func (q *QueryBuilder) Where(conditions ...Condition) *QueryBuilder {
+	q.where = append(q.where, conditions...)
+	return q
+}
+
+// GroupBy adds fields to the GROUP BY clause.
+func (q *QueryBuilder) GroupBy(fields ...string) *QueryBuilder {
+	q.groupBy = append(q.groupBy, fields...)
+	return q
+}
+
+// Having adds conditions on grouped results. Conditions from every call are
+// combined with AND.
+func (q *QueryBuilder) Having(conditions ...Condition) *QueryBuilder {
+	q.having = append(q.having, conditions...)
+	return q
+}
+
+// OrderBy adds a field to the ORDER BY clause.
+func (q *QueryBuilder) OrderBy(field string, order Order) *QueryBuilder {
+	q.orderBy = append(q.orderBy, fmt.Sprintf("%s %s", field, order))
+	return q
+}
+
+// Limit sets the maximum number of records returned.
+func (q *QueryBuilder) Limit(limit int) *QueryBuilder {
+	q.limit = limit
+	return q
+}
+
+// Offset sets the number of records skipped.
+func (q *QueryBuilder) Offset(offset int) *QueryBuilder {
+	q.offset = offset
+	return q
+}
+
+// ForView updates the last viewed date of the records returned.
+func (q *QueryBuilder) ForView() *QueryBuilder {
+	q.scope = "FOR VIEW"
+	return q
+}
+
+// ForReference updates the last referenced date of the records returned.
+func (q *QueryBuilder) ForReference() *QueryBuilder {
+	q.scope = "FOR REFERENCE"
+	return q
+}
+
+// String returns the SOQL query.
+func (q *QueryBuilder) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "SELECT %s FROM %s", strings.Join(q.fields, ","), q.name)
+	if len(q.where) > 0 {
+		fmt.Fprintf(&b, " WHERE %s", joinConditions(q.where, "AND"))
+	}
+	if len(q.groupBy) > 0 {
+		fmt.Fprintf(&b, " GROUP BY %s", strings.Join(q.groupBy, ","))
+	}
+	if len(q.having) > 0 {
+		fmt.Fprintf(&b, " HAVING %s", joinConditions(q.having, "AND"))
+	}
+	if len(q.orderBy) > 0 {
+		fmt.Fprintf(&b, " ORDER BY %s", strings.Join(q.orderBy, ","))
+	}
+	if q.limit > 0 {
+		fmt.Fprintf(&b, " LIMIT %d", q.limit)
+	}
+	if q.offset > 0 {
+		fmt.Fprintf(&b, " OFFSET %d", q.offset)
+	}
+	if q.scope != "" {
+		fmt.Fprintf(&b, " %s", q.scope)
+	}
+	return b.String()
+}
+
+// Condition is a predicate in a WHERE or HAVING clause.
+type Condition interface {
+	soql() string
+}
+
+type comparison struct {
+	field    string
+	operator string
+	value    any
+}
+
+func (c *comparison) soql() string {
+	return fmt.Sprintf("%s %s %s", c.field, c.operator, literal(c.value))
+}
+
+// Eq matches records where field equals value.
+func Eq(field string, value any) Condition {
+	return &comparison{field: field, operator: "=", value: value}
+}
+
+// Ne matches records where field does not equal value.
+func Ne(field string, value any) Condition {
+	return &comparison{field: field, operator: "!=", value: value}
+}
+
+// Lt matches records where field is less than value.
+func Lt(field string, value any) Condition {
+	return &comparison{field: field, operator: "<", value: value}
+}
+
+// Le matches records where field is less than or equal to value.
+func Le(field string, value any) Condition {
+	return &comparison{field: field, operator: "<=", value: value}
+}
+
+// Gt matches records where field is greater than value.
+func Gt(field string, value any) Condition {
+	return &comparison{field: field, operator: ">", value: value}
+}
+
+// Ge matches records where field is greater than or equal to value.
+func Ge(field string, value any) Condition {
+	return &comparison{field: field, operator: ">=", value: value}
+}
+
+// Like matches records where field matches pattern.
+func Like(field string, pattern LikePattern) Condition {
+	return &comparison{field: field, operator: "LIKE", value: pattern}
+}
+
+// StartsWith matches records where field starts with prefix.
+func StartsWith(field string, prefix string) Condition {
+	return Like(field, EscapeLike(prefix)+"%")
+}
+
+// EndsWith matches records where field ends with suffix.
+func EndsWith(field string, suffix string) Condition {
+	return Like(field, "%"+EscapeLike(suffix))
+}
+
+// Contains matches records where field contains text.
+func Contains(field string, text string) Condition {
+	return Like(field, "%"+EscapeLike(text)+"%")
+}
+
+// In matches records where field equals one of values, which must be a
+// non-empty slice. An empty slice is written as IN (), which Salesforce
+// rejects as malformed, so check for one before building the query.
+func In(field string, values any) Condition {
+	return &comparison{field: field, operator: "IN", value: values}
+}
+
+// NotIn matches records where field equals none of values, which must be a
+// non-empty slice. An empty slice is written as NOT IN (), which Salesforce
+// rejects as malformed.
+func NotIn(field string, values any) Condition {
+	return &comparison{field: field, operator: "NOT IN", value: values}
+}
+
+// Includes matches records where the multi-select picklist field includes
+// any of values.
+func Includes(field string, values ...string) Condition {
+	return &comparison{field: field, operator: "INCLUDES", value: values}
+}
+
+// Excludes matches records where the multi-select picklist field includes
+// none of values.
+func Excludes(field string, values ...string) Condition {
+	return &comparison{field: field, operator: "EXCLUDES", value: values}
+}
+
+type group struct {
+	operator   string
+	conditions []Condition
+}
+
+func (g *group) soql() string {
+	if len(g.conditions) == 1 {
+		return g.conditions[0].soql()
+	}
+	return fmt.Sprintf("(%s)", joinConditions(g.conditions, g.operator))
+}
+
+// AllOf matches records that meet every condition.
+func AllOf(conditions ...Condition) Condition {
+	return &group{operator: "AND", conditions: conditions}
+}
+
+// AnyOf matches records that meet any condition.
+func AnyOf(conditions ...Condition) Condition {
+	return &group{operator: "OR", conditions: conditions}
+}
+
+type not struct {
+	condition Condition
+}
+
+func (n *not) soql() string {
+	return fmt.Sprintf("(NOT %s)", n.condition.soql())
+}
+
+// NoneOf matches records that meet none of the conditions.
+func NoneOf(conditions ...Condition) Condition {
+	return &not{condition: &group{operator: "OR", conditions: conditions}}
+}
+
+func joinConditions(conditions []Condition, operator string) string {
+	result := make([]string, 0, len(conditions))
+	for _, condition := range conditions {
+		result = append(result, condition.soql())
+	}
+	return strings.Join(result, fmt.Sprintf(" %s ", operator))
+}
+
+// literal formats v as a SOQL literal. Pointers are written as the value
+// they point to. Strings, and values of types without a SOQL literal form,
+// are quoted and escaped, using their String method when they have one.
+func literal(v any) string {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return "null"
+		}
+		value = value.Elem()
+	}
+	if !value.IsValid() {
+		return "null"
+	}
+	v = value.Interface()
+	switch v := v.(type) {
+	case string:
+		return quote(v)
+	case ID:
+		return quote(string(v))
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.UTC().Format(DateTimeLayout)
+	case Date:
+		return v.String()
+	case LikePattern:
+		return quoteLike(v)
+	}
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'f', -1, 64)
+	case reflect.String:
+		return quote(value.String())
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool())
+	case reflect.Slice, reflect.Array:
+		values := make([]string, 0, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			values = append(values, literal(value.Index(i).Interface()))
+		}
+		return fmt.Sprintf("(%s)", strings.Join(values, ","))
+	}
+	if s, ok := v.(fmt.Stringer); ok {
+		return quote(s.String())
+	}
+	return quote(fmt.Sprint(v))
+}
+
+// isEmptyList reports whether v is, or points to, a slice or array with no
+// elements, which has no SOQL literal form.
+func isEmptyList(v any) bool {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Pointer && !value.IsNil() {
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		return value.Len() == 0
+	}
+	return false
+}
+
+// quoteReplacer escapes the characters SOQL requires to be escaped in a
+// quoted string.
+var quoteReplacer = strings.NewReplacer(
+	`\`, `\\`,
+	`'`, `\'`,
+	`"`, `\"`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\t", `\t`,
+	"\b", `\b`,
+	"\f", `\f`,
+)
+
+func quote(s string) string {
+	return fmt.Sprintf("'%s'", quoteReplacer.Replace(s))
+}
+
+// LikePattern is a pattern for the LIKE operator, in which % matches any
+// characters and _ matches a single character. Use EscapeLike to match text
+// literally within a pattern, e.g. "%" + EscapeLike(input) + "%".
+type LikePattern string
+
+// likeReplacer escapes the wildcards, and the escape character, in text.
+var likeReplacer = strings.NewReplacer(
+	`\`, `\\`,
+	`%`, `\%`,
+	`_`, `\_`,
+)
+
+// EscapeLike returns a pattern that matches text literally.
+func EscapeLike(text string) LikePattern {
+	return LikePattern(likeReplacer.Replace(text))
+}
+
+// quoteLike quotes a pattern, keeping the escape sequences for wildcards and
+// backslashes and escaping everything else as quote does.
+func quoteLike(pattern LikePattern) string {
+	var b strings.Builder
+	b.WriteByte('\'')
+	s := string(pattern)
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(`%_\`, s[i+1]) >= 0 {
+			b.WriteString(s[i : i+2])
+			i++
+			continue
+		}
+		b.WriteString(quoteReplacer.Replace(s[i : i+1]))
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
+
+// FormatSOQL replaces each ? placeholder in query with the next argument,
+// formatted as a SOQL literal. Strings and IDs are quoted and escaped,
+// time.Time is written as a DateTime and Date as a date, slices are written
+// as a list for use with IN, and LikePattern keeps its wildcards. Pointers
+// are written as the value they point to, or null. Empty lists are rejected.
+// Question marks within quoted strings in query are left alone.
+func FormatSOQL(query string, args ...any) (string, error) {
+	var b strings.Builder
+	next := 0
+	quoted := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quoted && c == '\\' && i+1 < len(query):
+			b.WriteString(query[i : i+2])
+			i++
+			continue
+		case c == '\'':
+			quoted = !quoted
+		case !quoted && c == '?':
+			if next >= len(args) {
+				return "", fmt.Errorf("query has more placeholders than the %d arguments provided", len(args))
+			}
+			if isEmptyList(args[next]) {
+				return "", fmt.Errorf("argument %d is an empty list", next+1)
+			}
+			b.WriteString(literal(args[next]))
+			next++
+			continue
+		}
+		b.WriteByte(c)
+	}
+	if next < len(args) {
+		return "", fmt.Errorf("query has %d placeholders but %d arguments were provided", next, len(args))
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,114 @@
+package sfdc_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/joefitzgerald/sfdc"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+)
+
+func testSOQL(t *testing.T, when spec.G, it spec.S) {
+	type Opportunity struct {
+		ID     string  `json:"Id"`
+		Name   string  `json:"Name"`
+		Amount float64 `json:"Amount"`
+	}
+
+	var entity *sfdc.Entity[Opportunity]
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		entity = sfdc.NewEntity[Opportunity](&sfdc.Instance{})
+	})
+
+	when("building a query with Select()", func() {
+		it("defaults to the tagged fields", func() {
+			Expect(entity.Select().String()).To(Equal("SELECT Id,Name,Amount FROM Opportunity"))
+		})
+
+		it("uses the provided fields", func() {
+			Expect(entity.Select("Id", "Name").String()).To(Equal("SELECT Id,Name FROM Opportunity"))
+		})
+
+		it("builds every clause in order", func() {
+			q := entity.Select("StageName", "COUNT(Id)").
+				Where(sfdc.Gt("Amount", 1000.5), sfdc.AnyOf(sfdc.Eq("IsClosed", false), sfdc.Like("Name", "Acme%"))).
+				GroupBy("StageName").
+				Having(sfdc.Gt("COUNT(Id)", 1)).
+				OrderBy("StageName", sfdc.Descending).
+				Limit(10).
+				Offset(20).
+				ForView()
+			Expect(q.String()).To(Equal("SELECT StageName,COUNT(Id) FROM Opportunity WHERE Amount > 1000.5 AND (IsClosed = false OR Name LIKE 'Acme%') GROUP BY StageName HAVING COUNT(Id) > 1 ORDER BY StageName DESC LIMIT 10 OFFSET 20 FOR VIEW"))
+		})
+
+		it("formats and escapes values", func() {
+			since := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("EST", -5*60*60))
+			q := entity.Select("Id").Where(
+				sfdc.Eq("Name", `O'Brien \ Sons`),
+				sfdc.In("Id", []sfdc.ID{"006000000000001", "006000000000002"}),
+				sfdc.Ge("CreatedDate", since),
+				sfdc.NoneOf(sfdc.Eq("AccountId", nil)),
+				sfdc.Includes("Region__c", "East", "West"),
+			).ForReference()
+			Expect(q.String()).To(Equal(`SELECT Id FROM Opportunity WHERE Name = 'O\'Brien \\ Sons' AND Id IN ('006000000000001','006000000000002') AND CreatedDate >= 2024-01-02T08:04:05.000Z AND (NOT AccountId = null) AND Region__c INCLUDES ('East','West') FOR REFERENCE`))
+		})
+
+		it("writes an empty list as is", func() {
+			q := entity.Select("Id").Where(sfdc.In("Id", []sfdc.ID{}))
+			Expect(q.String()).To(Equal("SELECT Id FROM Opportunity WHERE Id IN ()"))
+		})
+
+		it("excludes deleted records", func() {
+			q := entity.Select("Id").Where(sfdc.NotDeleted())
+			Expect(q.String()).To(Equal("SELECT Id FROM Opportunity WHERE IsDeleted = false"))
+		})
+	})
+
+	when("matching text with LIKE", func() {
+		it("escapes wildcards in the text", func() {
+			q := entity.Select("Id").Where(sfdc.Contains("Name", `50%_off\'`))
+			Expect(q.String()).To(Equal(`SELECT Id FROM Opportunity WHERE Name LIKE '%50\%\_off\\\'%'`))
+		})
+
+		it("escapes a trailing backslash in a pattern", func() {
+			q := entity.Select("Id").Where(sfdc.Like("Name", sfdc.LikePattern(`x\' OR Name != '`)))
+			Expect(q.String()).To(Equal(`SELECT Id FROM Opportunity WHERE Name LIKE 'x\\\' OR Name != \''`))
+		})
+	})
+
+	when("using FormatSOQL()", func() {
+		it("binds each argument as a literal", func() {
+			closeDate := sfdc.Date(time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC))
+			q, err := sfdc.FormatSOQL("SELECT Id FROM Opportunity WHERE Name = ? AND Amount > ? AND CloseDate = ? AND Id IN ? AND Name LIKE ? AND Description != '?'",
+				"' OR Name != '", 10, closeDate, []string{"a", "b"}, "%"+sfdc.EscapeLike("100%")+"%")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(q).To(Equal(`SELECT Id FROM Opportunity WHERE Name = '\' OR Name != \'' AND Amount > 10 AND CloseDate = 2024-03-31 AND Id IN ('a','b') AND Name LIKE '%100\%%' AND Description != '?'`))
+		})
+
+		it("binds pointers, dates, times and named numbers as their values", func() {
+			since := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+			closeDate := sfdc.Date(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+			name := "Acme"
+			var missing *string
+			q, err := sfdc.FormatSOQL("WHERE CreatedDate > ? AND CloseDate = ? AND CreatedDate < ? AND CloseDate != ? AND Name = ? AND Owner = ? AND Duration__c = ?",
+				&since, &closeDate, since, closeDate, &name, missing, time.Duration(5))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(q).To(Equal(`WHERE CreatedDate > 2024-01-02T03:04:05.000Z AND CloseDate = 2024-01-02 AND CreatedDate < 2024-01-02T03:04:05.000Z AND CloseDate != 2024-01-02 AND Name = 'Acme' AND Owner = null AND Duration__c = 5`))
+		})
+
+		it("returns an error for an empty list", func() {
+			_, err := sfdc.FormatSOQL("SELECT Id FROM Opportunity WHERE Id IN ?", []string{})
+			Expect(err).To(MatchError(ContainSubstring("empty list")))
+		})
+
+		it("returns an error when the arguments do not match the placeholders", func() {
+			_, err := sfdc.FormatSOQL("SELECT Id FROM Opportunity WHERE Name = ?")
+			Expect(err).To(HaveOccurred())
+			_, err = sfdc.FormatSOQL("SELECT Id FROM Opportunity", "extra")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+}
@@ -0,0 +1,133 @@
+package sfdc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+)
+
+// treeRecordLimit is the maximum number of records, across all levels, in a
+// single tree request.
+const treeRecordLimit = 200
+
+type treeRequest struct {
+	Records []record `json:"records"`
+}
+
+type treeResponse struct {
+	HasErrors bool `json:"hasErrors"`
+	Results   []struct {
+		ReferenceID string      `json:"referenceId"`
+		ID          ID          `json:"id"`
+		Errors      []SaveError `json:"errors"`
+	} `json:"results"`
+}
+
+// CreateTree inserts records along with their child records using the sObject
+// Tree API. Child records are read from slice fields such as
+// Contacts []Contact, keyed by the relationship name from the field's sfdc
+// tag or JSON key, and typed by the name of the slice's element type.
+//
+// Each record is given a reference ID derived from its position, e.g.
+// Account_0 for the first record and Account_0_Contacts_1 for its second
+// contact. The result maps these reference IDs to the IDs of the created
+// records. The request is all or nothing.
+func (e *Entity[T]) CreateTree(ctx context.Context, records []T) (map[string]ID, error) {
+	uri, err := e.instance.CompositeURL("tree", e.name)
+	if err != nil {
+		return nil, err
+	}
+	body := treeRequest{Records: make([]record, 0, len(records))}
+	count := 0
+	for i := range records {
+		r, err := newTreeRecord(reflect.ValueOf(&records[i]).Elem(), e.name, fmt.Sprintf("%s_%d", e.name, i), &count)
+		if err != nil {
+			return nil, err
+		}
+		body.Records = append(body.Records, r)
+	}
+	if count > treeRecordLimit {
+		return nil, fmt.Errorf("tree requests are limited to %d records, got %d", treeRecordLimit, count)
+	}
+
+	res, err := e.instance.sendJSON(ctx, http.MethodPost, uri.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	var r treeResponse
+	if err := json.Unmarshal(b, &r); err != nil {
+		if res.StatusCode >= 400 {
+			return nil, responseError(res, b)
+		}
+		return nil, err
+	}
+	if r.HasErrors {
+		var errs []error
+		for _, result := range r.Results {
+			for _, saveError := range result.Errors {
+				errs = append(errs, fmt.Errorf("%s: %s (%s)", result.ReferenceID, saveError.Message, saveError.StatusCode))
+			}
+		}
+		if len(errs) == 0 {
+			errs = append(errs, errors.New("tree request failed"))
+		}
+		return nil, errors.Join(errs...)
+	}
+	result := make(map[string]ID, len(r.Results))
+	for _, rec := range r.Results {
+		result[rec.ReferenceID] = rec.ID
+	}
+	return result, nil
+}
+
+// newTreeRecord encodes v, and recursively its child relationships, as a
+// tree record with the given type and reference ID. count is incremented for
+// every record encoded.
+func newTreeRecord(v reflect.Value, name string, referenceID string, count *int) (record, error) {
+	*count++
+	result, err := newRecord(v.Interface(), readOnlyFieldsForType(v.Type()), false)
+	if err != nil {
+		return nil, err
+	}
+	if err := result.setAttributes(map[string]string{"type": name, "referenceId": referenceID}); err != nil {
+		return nil, err
+	}
+	for _, field := range deepFields(v.Type()) {
+		if !isChildRelationship(field) {
+			continue
+		}
+		children := v.FieldByName(field.Name)
+		if children.Len() == 0 {
+			continue
+		}
+		relationship := childRelationshipName(field)
+		elemType := sliceElem(field.Type)
+		records := make([]record, 0, children.Len())
+		for j := 0; j < children.Len(); j++ {
+			child := reflect.Indirect(children.Index(j))
+			if !child.IsValid() {
+				continue
+			}
+			r, err := newTreeRecord(child, elemType.Name(), fmt.Sprintf("%s_%s_%d", referenceID, relationship, j), count)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, r)
+		}
+		b, err := json.Marshal(treeRequest{Records: records})
+		if err != nil {
+			return nil, err
+		}
+		result[relationship] = b
+	}
+	return result, nil
+}
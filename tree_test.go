@@ -0,0 +1,102 @@
+package sfdc_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/joefitzgerald/sfdc"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+)
+
+func testTree(t *testing.T, when spec.G, it spec.S) {
+	type Contact struct {
+		LastName string `json:"LastName,omitempty"`
+	}
+
+	type Account struct {
+		ID       string    `json:"Id,omitempty"`
+		Name     string    `json:"Name,omitempty"`
+		Contacts []Contact `json:"Contacts,omitempty"`
+	}
+
+	var (
+		server   *httptest.Server
+		handler  func(w http.ResponseWriter, r *http.Request)
+		accounts *sfdc.Entity[Account]
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler(w, r)
+		}))
+		instance, err := sfdc.New(sfdc.WithNoAuthentication(), sfdc.WithURL(server.URL))
+		Expect(err).NotTo(HaveOccurred())
+		accounts = sfdc.NewEntity[Account](instance)
+	})
+
+	it.After(func() {
+		server.Close()
+	})
+
+	it("posts nested records and returns the created IDs", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.Method).To(Equal(http.MethodPost))
+			Expect(r.URL.Path).To(Equal("/services/data/v54.0/composite/tree/Account"))
+			var body map[string]any
+			Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+			Expect(body).To(Equal(map[string]any{
+				"records": []any{
+					map[string]any{
+						"attributes": map[string]any{"type": "Account", "referenceId": "Account_0"},
+						"Name":       "Acme",
+						"Contacts": map[string]any{
+							"records": []any{
+								map[string]any{
+									"attributes": map[string]any{"type": "Contact", "referenceId": "Account_0_Contacts_0"},
+									"LastName":   "Smith",
+								},
+							},
+						},
+					},
+				},
+			}))
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"hasErrors":false,"results":[
+				{"referenceId":"Account_0","id":"001000000000001"},
+				{"referenceId":"Account_0_Contacts_0","id":"003000000000001"}
+			]}`))
+		}
+		ids, err := accounts.CreateTree(context.Background(), []Account{{
+			Name:     "Acme",
+			Contacts: []Contact{{LastName: "Smith"}},
+		}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ids).To(Equal(map[string]sfdc.ID{
+			"Account_0":            "001000000000001",
+			"Account_0_Contacts_0": "003000000000001",
+		}))
+	})
+
+	it("returns the errors for each failed record", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"hasErrors":true,"results":[
+				{"referenceId":"Account_0_Contacts_0","errors":[{"statusCode":"REQUIRED_FIELD_MISSING","message":"Required fields are missing: [LastName]","fields":["LastName"]}]}
+			]}`))
+		}
+		_, err := accounts.CreateTree(context.Background(), []Account{{Name: "Acme", Contacts: []Contact{{}}}})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Account_0_Contacts_0"))
+		Expect(err.Error()).To(ContainSubstring("REQUIRED_FIELD_MISSING"))
+	})
+
+	it("rejects more than 200 records", func() {
+		_, err := accounts.CreateTree(context.Background(), make([]Account, 201))
+		Expect(err).To(HaveOccurred())
+	})
+}
@@ -0,0 +1,120 @@
+package sfdc
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIUsage is the org's use of its daily API request allocation, as last
+// reported by Salesforce.
+type APIUsage struct {
+	// Used is the number of API requests made in the last 24 hours.
+	Used int
+	// Max is the number of API requests allowed in 24 hours.
+	Max int
+	// Updated is when the usage was reported.
+	Updated time.Time
+}
+
+// Remaining returns the number of API requests left in the allocation.
+func (u APIUsage) Remaining() int {
+	return max(u.Max-u.Used, 0)
+}
+
+// Fraction returns the fraction of the allocation that has been used, or 0
+// when the usage is unknown.
+func (u APIUsage) Fraction() float64 {
+	if u.Max <= 0 {
+		return 0
+	}
+	return float64(u.Used) / float64(u.Max)
+}
+
+type withAPIUsageThresholds struct {
+	fn         func(usage APIUsage, threshold float64)
+	thresholds []float64
+}
+
+func (w *withAPIUsageThresholds) applyToInstance(i *Instance) {
+	if w.fn == nil {
+		return
+	}
+	i.usageThresholds = w.thresholds
+	i.usageCrossed = make([]bool, len(w.thresholds))
+	i.onUsageThreshold = w.fn
+}
+
+// WithAPIUsageThresholds calls fn when the fraction of the daily API
+// allocation used crosses one of thresholds, e.g. 0.8 for 80%. It is called
+// with the usage reported by the response that crossed the threshold, once
+// for each threshold crossed, and may be called concurrently. A threshold is
+// not crossed again until usage has fallen below half of it, as it does when
+// the allocation resets, so responses that arrive out of order do not call fn
+// twice.
+func WithAPIUsageThresholds(fn func(usage APIUsage, threshold float64), thresholds ...float64) InstanceOption {
+	return &withAPIUsageThresholds{fn: fn, thresholds: thresholds}
+}
+
+// APIUsage returns the API usage reported by the most recent response. It
+// is the zero APIUsage until a response reports it.
+func (i *Instance) APIUsage() APIUsage {
+	i.usageMu.Lock()
+	defer i.usageMu.Unlock()
+	return i.usage
+}
+
+// recordUsage records the API usage reported by res, if any, and calls the
+// threshold callback for each threshold crossed that has not already been.
+func (i *Instance) recordUsage(res *http.Response) {
+	if res == nil {
+		return
+	}
+	usage, ok := parseAPIUsage(res.Header.Get("Sforce-Limit-Info"))
+	if !ok {
+		return
+	}
+	usage.Updated = time.Now()
+	crossed := []float64{}
+	i.usageMu.Lock()
+	i.usage = usage
+	for n, threshold := range i.usageThresholds {
+		switch {
+		case !i.usageCrossed[n] && usage.Fraction() >= threshold:
+			i.usageCrossed[n] = true
+			crossed = append(crossed, threshold)
+		case i.usageCrossed[n] && usage.Fraction() < threshold/2:
+			i.usageCrossed[n] = false
+		}
+	}
+	i.usageMu.Unlock()
+	for _, threshold := range crossed {
+		i.onUsageThreshold(usage, threshold)
+	}
+}
+
+// parseAPIUsage parses the api-usage entry of a Sforce-Limit-Info header,
+// such as "api-usage=18/5000; per-app-api-usage=17/250(appName=sample)".
+func parseAPIUsage(header string) (APIUsage, bool) {
+	for entry := range strings.FieldsFuncSeq(header, func(r rune) bool { return r == ';' || r == ',' }) {
+		value, ok := strings.CutPrefix(strings.TrimSpace(entry), "api-usage=")
+		if !ok {
+			continue
+		}
+		used, limit, ok := strings.Cut(value, "/")
+		if !ok {
+			return APIUsage{}, false
+		}
+		u, err := strconv.Atoi(used)
+		if err != nil {
+			return APIUsage{}, false
+		}
+		m, err := strconv.Atoi(limit)
+		if err != nil {
+			return APIUsage{}, false
+		}
+		return APIUsage{Used: u, Max: m}, true
+	}
+	return APIUsage{}, false
+}
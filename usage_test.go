@@ -0,0 +1,122 @@
+package sfdc_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/joefitzgerald/sfdc"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+)
+
+func testUsage(t *testing.T, when spec.G, it spec.S) {
+	type Account struct {
+		ID string `json:"Id"`
+	}
+
+	var (
+		server *httptest.Server
+		usage  string
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if usage != "" {
+				w.Header().Set("Sforce-Limit-Info", usage)
+			}
+			w.Write([]byte(`{"Id":"001"}`))
+		}))
+	})
+
+	it.After(func() {
+		server.Close()
+	})
+
+	it("records the usage reported by the latest response", func() {
+		instance, err := sfdc.New(sfdc.WithNoAuthentication(), sfdc.WithURL(server.URL))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(instance.APIUsage()).To(Equal(sfdc.APIUsage{}))
+
+		usage = "api-usage=18/5000; per-app-api-usage=17/250(appName=sample)"
+		before := time.Now()
+		_, err = sfdc.NewEntity[Account](instance).Get(context.Background(), "001")
+		Expect(err).NotTo(HaveOccurred())
+		result := instance.APIUsage()
+		Expect(result.Used).To(Equal(18))
+		Expect(result.Max).To(Equal(5000))
+		Expect(result.Remaining()).To(Equal(4982))
+		Expect(result.Updated).To(BeTemporally(">=", before))
+
+		usage = ""
+		_, err = sfdc.NewEntity[Account](instance).Get(context.Background(), "001")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(instance.APIUsage().Used).To(Equal(18))
+	})
+
+	it("calls the callback when usage crosses a threshold", func() {
+		var crossed []float64
+		instance, err := sfdc.New(sfdc.WithNoAuthentication(), sfdc.WithURL(server.URL),
+			sfdc.WithAPIUsageThresholds(func(usage sfdc.APIUsage, threshold float64) {
+				crossed = append(crossed, threshold)
+			}, 0.5, 0.8, 0.9))
+		Expect(err).NotTo(HaveOccurred())
+		accounts := sfdc.NewEntity[Account](instance)
+
+		for _, used := range []string{"40", "60", "70", "95", "96"} {
+			usage = "api-usage=" + used + "/100"
+			_, err := accounts.Get(context.Background(), "001")
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(crossed).To(Equal([]float64{0.5, 0.8, 0.9}))
+	})
+
+	it("calls the callback once for responses that arrive out of order", func() {
+		var crossed []float64
+		instance, err := sfdc.New(sfdc.WithNoAuthentication(), sfdc.WithURL(server.URL),
+			sfdc.WithAPIUsageThresholds(func(usage sfdc.APIUsage, threshold float64) {
+				crossed = append(crossed, threshold)
+			}, 0.8))
+		Expect(err).NotTo(HaveOccurred())
+		accounts := sfdc.NewEntity[Account](instance)
+
+		for _, used := range []string{"81", "79", "81", "60", "85", "10", "85"} {
+			usage = "api-usage=" + used + "/100"
+			_, err := accounts.Get(context.Background(), "001")
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(crossed).To(Equal([]float64{0.8, 0.8}))
+	})
+
+	it("calls the callback once for concurrent responses", func() {
+		concurrent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Sforce-Limit-Info", "api-usage="+path.Base(r.URL.Path)+"/100")
+			w.Write([]byte(`{"Id":"001"}`))
+		}))
+		defer concurrent.Close()
+		var calls atomic.Int32
+		instance, err := sfdc.New(sfdc.WithNoAuthentication(), sfdc.WithURL(concurrent.URL),
+			sfdc.WithAPIUsageThresholds(func(usage sfdc.APIUsage, threshold float64) {
+				calls.Add(1)
+			}, 0.8))
+		Expect(err).NotTo(HaveOccurred())
+		accounts := sfdc.NewEntity[Account](instance)
+
+		var wg sync.WaitGroup
+		for n := range 50 {
+			wg.Go(func() {
+				_, err := accounts.Get(context.Background(), sfdc.ID(strconv.Itoa(79+n%3)))
+				Expect(err).NotTo(HaveOccurred())
+			})
+		}
+		wg.Wait()
+		Expect(calls.Load()).To(BeEquivalentTo(1))
+	})
+}